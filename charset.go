@@ -0,0 +1,194 @@
+package struct2csv
+
+import (
+	"fmt"
+	"io"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// Charset is the character encoding of a Writer's output.
+type Charset int
+
+const (
+	// UTF8 is the default.
+	UTF8 Charset = iota
+	// UTF16LE is little-endian UTF-16.
+	UTF16LE
+	// UTF16BE is big-endian UTF-16.
+	UTF16BE
+	// Latin1 is ISO-8859-1.
+	Latin1
+	// Windows1252 is the Windows Western European code page, a superset of
+	// the printable characters of ISO-8859-1.
+	Windows1252
+	// ASCII is 7-bit US-ASCII.
+	ASCII
+)
+
+var charsetNames = [...]string{"UTF-8", "UTF-16LE", "UTF-16BE", "ISO-8859-1", "Windows-1252", "US-ASCII"}
+
+func (c Charset) String() string {
+	if c < 0 || int(c) >= len(charsetNames) {
+		return fmt.Sprintf("Charset(%d)", int(c))
+	}
+	return charsetNames[c]
+}
+
+// singleByte returns whether or not the charset encodes each rune as a
+// single byte.
+func (c Charset) singleByte() bool {
+	return c == Latin1 || c == Windows1252 || c == ASCII
+}
+
+// An UnrepresentableRuneError is returned when output contains a rune that
+// can't be encoded using the Writer's Charset.  Invalid UTF-8 is reported as
+// utf8.RuneError.
+type UnrepresentableRuneError struct {
+	Rune    rune
+	Charset Charset
+}
+
+func (e UnrepresentableRuneError) Error() string {
+	return fmt.Sprintf("struct2csv: %U can't be represented in %s", e.Rune, e.Charset)
+}
+
+// windows1252 maps the runes of bytes 0x80-0x9F in Windows-1252 to those
+// bytes; the rest of the bytes are the same as ISO-8859-1.
+var windows1252 = map[rune]byte{
+	'€': 0x80, '‚': 0x82, 'ƒ': 0x83, '„': 0x84,
+	'…': 0x85, '†': 0x86, '‡': 0x87, 'ˆ': 0x88,
+	'‰': 0x89, 'Š': 0x8A, '‹': 0x8B, 'Œ': 0x8C,
+	'Ž': 0x8E, '‘': 0x91, '’': 0x92, '“': 0x93,
+	'”': 0x94, '•': 0x95, '–': 0x96, '—': 0x97,
+	'˜': 0x98, '™': 0x99, 'š': 0x9A, '›': 0x9B,
+	'œ': 0x9C, 'ž': 0x9E, 'Ÿ': 0x9F,
+}
+
+// transcoder is an io.Writer that transcodes UTF-8 to a Charset before
+// writing it to w.  Incomplete UTF-8 sequences at the end of a Write are
+// held until the next one.  If repl is 0, unrepresentable runes result in
+// an UnrepresentableRuneError; otherwise they are replaced by repl.
+type transcoder struct {
+	w       io.Writer
+	charset Charset
+	repl    rune
+	pending []byte // the start of an incomplete UTF-8 sequence
+	started bool   // whether or not any runes have been written
+	buf     []byte
+}
+
+func newTranscoder(w io.Writer, c Charset, repl rune) *transcoder {
+	return &transcoder{w: w, charset: c, repl: repl}
+}
+
+// Write transcodes p and writes it to the underlying io.Writer.  The
+// returned count is the number of bytes of p that were consumed.
+func (t *transcoder) Write(p []byte) (int, error) {
+	n := len(p)
+	if len(t.pending) > 0 {
+		p = append(t.pending, p...)
+	}
+	consumed := -len(t.pending)
+	t.buf = t.buf[:0]
+	var err error
+	for len(p) > 0 {
+		if !utf8.FullRune(p) {
+			break
+		}
+		r, size := utf8.DecodeRune(p)
+		if r == utf8.RuneError && size == 1 {
+			// invalid UTF-8 can't be represented in any charset
+			err = t.encode(r, false)
+		} else {
+			err = t.encode(r, true)
+		}
+		if err != nil {
+			break
+		}
+		p = p[size:]
+		consumed += size
+	}
+	if err == nil {
+		// hold on to the incomplete sequence
+		t.pending = append(t.pending[:0], p...)
+		consumed = n
+	} else {
+		t.pending = t.pending[:0]
+		if consumed < 0 {
+			consumed = 0
+		}
+	}
+	_, werr := t.w.Write(t.buf)
+	if werr != nil {
+		return 0, werr
+	}
+	return consumed, err
+}
+
+// encode appends the encoded rune to buf.  If valid is false, the rune
+// wasn't valid UTF-8.
+func (t *transcoder) encode(r rune, valid bool) error {
+	first := !t.started
+	t.started = true
+	if t.charset.singleByte() && first && r == '\uFEFF' {
+		// byte order marks are only meaningful for Unicode
+		return nil
+	}
+	if !valid {
+		return t.replace(r)
+	}
+	switch t.charset {
+	case UTF16LE, UTF16BE:
+		r1, r2 := utf16.EncodeRune(r)
+		if r1 == utf8.RuneError {
+			t.appendUTF16(uint16(r))
+			return nil
+		}
+		t.appendUTF16(uint16(r1))
+		t.appendUTF16(uint16(r2))
+		return nil
+	case Latin1:
+		if r > 0xFF {
+			return t.replace(r)
+		}
+	case Windows1252:
+		if b, ok := windows1252[r]; ok {
+			t.buf = append(t.buf, b)
+			return nil
+		}
+		if r > 0xFF || (r >= 0x80 && r <= 0x9F) {
+			return t.replace(r)
+		}
+	case ASCII:
+		if r > 0x7F {
+			return t.replace(r)
+		}
+	default:
+		t.buf = utf8.AppendRune(t.buf, r)
+		return nil
+	}
+	t.buf = append(t.buf, byte(r))
+	return nil
+}
+
+// replace encodes the replacement rune in place of r, or returns an
+// UnrepresentableRuneError if there isn't one.
+func (t *transcoder) replace(r rune) error {
+	if t.repl == 0 {
+		return UnrepresentableRuneError{Rune: r, Charset: t.charset}
+	}
+	repl := t.repl
+	t.repl = 0
+	err := t.encode(repl, true)
+	t.repl = repl
+	return err
+}
+
+func (t *transcoder) appendUTF16(u uint16) {
+	if t.charset == UTF16LE {
+		t.buf = append(t.buf, byte(u), byte(u>>8))
+		return
+	}
+	t.buf = append(t.buf, byte(u>>8), byte(u))
+}
@@ -0,0 +1,67 @@
+package struct2csv
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+func TestCharset(t *testing.T) {
+	tsts := []struct {
+		charset  Charset
+		repl     rune
+		bom      bool
+		row      []string
+		expected []byte
+		err      error
+	}{
+		{UTF8, 0, true, []string{"é"}, []byte("\xef\xbb\xbf\xc3\xa9\n"), nil},
+		{UTF16LE, 0, true, []string{"é", "𝄞"}, []byte{0xFF, 0xFE, 0xE9, 0x00, ',', 0x00, 0x34, 0xD8, 0x1E, 0xDD, '\n', 0x00}, nil},
+		{UTF16BE, 0, false, []string{"é"}, []byte{0x00, 0xE9, 0x00, '\n'}, nil},
+		{Latin1, 0, true, []string{"café"}, []byte("caf\xe9\n"), nil},
+		{Latin1, 0, false, []string{"5€"}, []byte("5"), UnrepresentableRuneError{Rune: '€', Charset: Latin1}},
+		{Latin1, '?', false, []string{"5€"}, []byte("5?\n"), nil},
+		{Windows1252, 0, false, []string{"5€", "“x”"}, []byte("5\x80,\x93x\x94\n"), nil},
+		{ASCII, '?', false, []string{"naïve"}, []byte("na?ve\n"), nil},
+		{ASCII, 'é', false, []string{"naïve"}, []byte("na"), UnrepresentableRuneError{Rune: 'é', Charset: ASCII}},
+	}
+	for i, test := range tsts {
+		buff := &bytes.Buffer{}
+		w := NewWriter(buff)
+		w.SetBOM(test.bom)
+		w.SetCharset(test.charset)
+		w.SetCharsetReplacement(test.repl)
+		err := w.Write(test.row)
+		if err != nil {
+			t.Errorf("%d: unexpected error: %s", i, err)
+			continue
+		}
+		w.Flush()
+		err = w.Error()
+		if !errors.Is(err, test.err) {
+			t.Errorf("%d: expected error %v, got %v", i, test.err, err)
+		}
+		if !bytes.Equal(buff.Bytes(), test.expected) {
+			t.Errorf("%d: expected %q, got %q", i, test.expected, buff.Bytes())
+		}
+	}
+}
+
+func TestTranscoderPartialRunes(t *testing.T) {
+	buff := &bytes.Buffer{}
+	tc := newTranscoder(buff, UTF16BE, 0)
+	// é is split between writes
+	for _, p := range [][]byte{[]byte("a\xc3"), []byte("\xa9b")} {
+		n, err := tc.Write(p)
+		if err != nil {
+			t.Errorf("unexpected error: %s", err)
+		}
+		if n != len(p) {
+			t.Errorf("expected %d bytes to be consumed, got %d", len(p), n)
+		}
+	}
+	expected := []byte{0x00, 'a', 0x00, 0xE9, 0x00, 'b'}
+	if !bytes.Equal(buff.Bytes(), expected) {
+		t.Errorf("expected %q, got %q", expected, buff.Bytes())
+	}
+}
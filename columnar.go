@@ -0,0 +1,305 @@
+package struct2csv
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// A Column holds the values of a column of data encoded by MarshalColumns.
+// The values are in one of the slices, depending on the column's type:
+// Ints for signed integers, Uints for unsigned integers, Floats for floats,
+// Bools for bools, Times for time.Time, and Strings for everything else,
+// which are encoded as they would be for CSV.  Numbers aren't affected by the
+// Encoder's number formatting settings.  The values of a driver.Valuer are
+// in the slice for the type of the values its Value method returns, or in
+// Strings if they aren't all of the same type.
+//
+// Nulls is a bitmap of the NULL values: if value i is NULL, bit i%8 of byte
+// i/8 is set.  The slice holding the values has the zero value for NULL
+// values.
+type Column struct {
+	// Index is the position of the column.
+	Index int
+	// Kind is the Kind of the column's field, as in ColumnSchema.
+	Kind    reflect.Kind
+	Ints    []int64
+	Uints   []uint64
+	Floats  []float64
+	Bools   []bool
+	Times   []time.Time
+	Strings []string
+	Nulls   []byte
+	n       int
+}
+
+// Len returns the number of values in the column.
+func (c Column) Len() int {
+	return c.n
+}
+
+// IsNull returns whether or not value i is NULL.
+func (c Column) IsNull(i int) bool {
+	return c.Nulls[i/8]&(1<<(uint(i)%8)) != 0
+}
+
+// A DuplicateColumnError is returned by MarshalColumns when columns have the
+// same name, even after qualifying them with the names of the structs they
+// are nested in.
+type DuplicateColumnError struct {
+	Name string
+}
+
+func (e DuplicateColumnError) Error() string {
+	return fmt.Sprintf("struct2csv: duplicate column: %q", e.Name)
+}
+
+// MarshalColumns takes a slice of structs and returns its data by column,
+// keyed by column name.  Columns whose names aren't unique, e.g. the fields
+// of two struct fields of the same type, are keyed by their names qualified
+// by the names of the structs they are nested in, e.g. "Home.Street".
+//
+// If a field can't be encoded, what happens depends on the ErrorPolicy, as
+// it does for Marshal; for PlaceholderOnError, the field is NULL unless it
+// is in a column of strings, which gets the placeholder.
+func (e *Encoder) MarshalColumns(v interface{}) (map[string]Column, error) {
+	val, err := structSlice(v)
+	if err != nil {
+		return nil, err
+	}
+	e.getColNames(val.Index(0).Interface())
+	plan := e.columns(val.Type().Elem(), nil, nil)
+	keys, err := columnKeys(plan)
+	if err != nil {
+		return nil, err
+	}
+	schema := make([]ColumnSchema, len(plan))
+	cols := make([]Column, len(plan))
+	// the values of Valuer columns, whose type is known once all of the
+	// values are
+	valuers := make(map[int][]cell)
+	for i, col := range plan {
+		schema[i] = e.columnSchema(col)
+		cols[i] = Column{Index: i, Kind: schema[i].Kind}
+		if isValuerColumn(col) {
+			valuers[i] = nil
+		}
+	}
+	var errs MultiError
+	for i := 0; i < val.Len(); i++ {
+		strs, placeholders, err := e.marshalFields(val.Index(i), false)
+		if err != nil {
+			// the header would be row 0
+			err = rowError(err, i, i+1)
+			if e.policy == AbortOnError {
+				return nil, err
+			}
+			errs = appendErrors(errs, err)
+		}
+		if strs == nil {
+			continue
+		}
+		if len(strs) != len(plan) {
+			return nil, FieldCountError{Expected: len(plan), Got: len(strs)}
+		}
+		for j, col := range plan {
+			c := cell{s: strs[j]}
+			fv, ok := fieldByIndex(val.Index(i), col.index)
+			if ok {
+				c.val, c.ok = columnValue(fv)
+			}
+			if placeholders != nil && placeholders[j] {
+				c.val, c.ok, c.placeholder = reflect.Value{}, false, true
+			}
+			if _, ok := valuers[j]; ok {
+				valuers[j] = append(valuers[j], c)
+				continue
+			}
+			cols[j].append(schema[j].native, c)
+		}
+	}
+	for j, cells := range valuers {
+		typ := valuerColumnType(cells, schema[j].native)
+		for _, c := range cells {
+			cols[j].append(typ, c)
+		}
+	}
+	m := make(map[string]Column, len(cols))
+	for i, key := range keys {
+		m[key] = cols[i]
+	}
+	if len(errs) > 0 {
+		return m, errs
+	}
+	return m, nil
+}
+
+// columnKeys returns unique keys for the columns.
+func columnKeys(cols []column) ([]string, error) {
+	count := make(map[string]int, len(cols))
+	for _, col := range cols {
+		count[col.name]++
+	}
+	keys := make([]string, len(cols))
+	seen := make(map[string]bool, len(cols))
+	for i, col := range cols {
+		keys[i] = col.name
+		if count[col.name] > 1 {
+			keys[i] = strings.Join(append(col.groups[:len(col.groups):len(col.groups)], col.name), ".")
+		}
+		if seen[keys[i]] {
+			return nil, DuplicateColumnError{Name: keys[i]}
+		}
+		seen[keys[i]] = true
+	}
+	return keys, nil
+}
+
+// columnValue returns the value of a column's field, following pointers
+// and using the values of driver.Valuers.  False is returned for NULL
+// values.
+func columnValue(val reflect.Value) (reflect.Value, bool) {
+	for {
+		if val.Kind() == reflect.Ptr && val.IsNil() {
+			return reflect.Value{}, false
+		}
+		if isValuer(val.Type()) {
+			v, err := val.Interface().(driver.Valuer).Value()
+			if err != nil || v == nil {
+				return reflect.Value{}, false
+			}
+			return reflect.ValueOf(v), true
+		}
+		if val.Kind() != reflect.Ptr {
+			return val, true
+		}
+		val = val.Elem()
+	}
+}
+
+// cell is a value of a column; s is the value encoded as a string.  If ok
+// is false, the value is NULL, unless it is a placeholder, which is written
+// to columns of strings.
+type cell struct {
+	val         reflect.Value
+	ok          bool
+	placeholder bool
+	s           string
+}
+
+// isValuerColumn returns whether or not col's values come from the Value
+// method of a driver.Valuer.
+func isValuerColumn(col column) bool {
+	if col.opts.formatter != "" {
+		return false
+	}
+	for typ := col.typ; ; typ = typ.Elem() {
+		if isValuer(typ) {
+			return true
+		}
+		if typ.Kind() != reflect.Ptr {
+			return false
+		}
+	}
+}
+
+// valuerColumnType returns the type of a Valuer column, whose type from its
+// schema is typ, from the dynamic types of its values.  If the values don't
+// all fit typ, the column is strings.  Valuers that aren't database/sql
+// Null types are strings in the schema; their column has the type of their
+// values, if they are all of the same type.
+func valuerColumnType(cells []cell, typ dataType) dataType {
+	var dyn dataType
+	first := true
+	for _, c := range cells {
+		if !c.ok {
+			continue
+		}
+		t := kindType(c.val.Kind())
+		if c.val.Type() == timeType {
+			t = typeDateTime
+		}
+		switch {
+		case typ != typeString:
+			// ints and uints are interchangeable, e.g. sql.NullByte
+			// returns an int64
+			isInt := t == typeInteger || t == typeUnsigned
+			if t != typ && !(isInt && (typ == typeInteger || typ == typeUnsigned)) {
+				return typeString
+			}
+		case first:
+			dyn, first = t, false
+		case t != dyn:
+			return typeString
+		}
+	}
+	if typ == typeString && !first {
+		return dyn
+	}
+	return typ
+}
+
+// append appends c, a value of type typ, to the column.
+func (c *Column) append(typ dataType, v cell) {
+	if c.n%8 == 0 {
+		c.Nulls = append(c.Nulls, 0)
+	}
+	ok := v.ok || (v.placeholder && typ == typeString)
+	if !ok {
+		c.Nulls[c.n/8] |= 1 << (uint(c.n) % 8)
+	}
+	c.n++
+	val := v.val
+	switch typ {
+	case typeInteger:
+		var i int64
+		if ok {
+			i = toInt64(val)
+		}
+		c.Ints = append(c.Ints, i)
+	case typeUnsigned:
+		var u uint64
+		if ok {
+			// converting back to uint64 keeps the value
+			u = uint64(toInt64(val))
+		}
+		c.Uints = append(c.Uints, u)
+	case typeNumber:
+		var f float64
+		if ok {
+			f = val.Float()
+		}
+		c.Floats = append(c.Floats, f)
+	case typeBoolean:
+		var b bool
+		if ok {
+			b = val.Bool()
+		}
+		c.Bools = append(c.Bools, b)
+	case typeDateTime:
+		var t time.Time
+		if ok {
+			t = val.Interface().(time.Time)
+		}
+		c.Times = append(c.Times, t)
+	default:
+		s := v.s
+		if !ok {
+			s = ""
+		}
+		c.Strings = append(c.Strings, s)
+	}
+}
+
+// toInt64 returns the value of val, an int or uint, as an int64; the
+// database/sql Null types, like sql.NullByte, return int64 values for
+// fields of other kinds.
+func toInt64(val reflect.Value) int64 {
+	switch val.Kind() {
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return int64(val.Uint())
+	}
+	return val.Int()
+}
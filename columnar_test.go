@@ -0,0 +1,131 @@
+package struct2csv
+
+import (
+	"database/sql"
+	"errors"
+	"math"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestMarshalColumns(t *testing.T) {
+	type Point struct {
+		X int `csv:"x"`
+	}
+	type Reading struct {
+		Sensor string          `csv:"sensor"`
+		Value  float64         `csv:"value,format=f,prec=1"`
+		Count  *uint32         `csv:"count"`
+		OK     bool            `csv:"ok"`
+		Flag   sql.NullByte    `csv:"flag"`
+		Temp   sql.NullFloat64 `csv:"temp"`
+		At     time.Time       `csv:"at"`
+		From   Point           `csv:"from"`
+		To     Point           `csv:"to"`
+	}
+	n := uint32(7)
+	at := time.Date(2015, 11, 22, 10, 30, 0, 0, time.UTC)
+	data := []Reading{
+		Reading{Sensor: "a", Value: math.NaN(), Count: &n, OK: true, Flag: sql.NullByte{Byte: 3, Valid: true}, At: at, From: Point{1}, To: Point{2}},
+		Reading{Sensor: "b", Value: 1.5, Temp: sql.NullFloat64{Float64: 20.5, Valid: true}},
+	}
+	cols, err := New().MarshalColumns(data)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if len(cols) != 9 {
+		t.Errorf("expected 9 columns, got %d", len(cols))
+	}
+	expected := map[string]Column{
+		"sensor": {Index: 0, Kind: reflect.String, Strings: []string{"a", "b"}, Nulls: []byte{0}, n: 2},
+		"count":  {Index: 2, Kind: reflect.Uint32, Uints: []uint64{7, 0}, Nulls: []byte{2}, n: 2},
+		"ok":     {Index: 3, Kind: reflect.Bool, Bools: []bool{true, false}, Nulls: []byte{0}, n: 2},
+		"flag":   {Index: 4, Kind: reflect.Uint8, Uints: []uint64{3, 0}, Nulls: []byte{2}, n: 2},
+		"temp":   {Index: 5, Kind: reflect.Float64, Floats: []float64{0, 20.5}, Nulls: []byte{1}, n: 2},
+		"at":     {Index: 6, Kind: reflect.Struct, Times: []time.Time{at, {}}, Nulls: []byte{0}, n: 2},
+		"from.x": {Index: 7, Kind: reflect.Int, Ints: []int64{1, 0}, Nulls: []byte{0}, n: 2},
+		"to.x":   {Index: 8, Kind: reflect.Int, Ints: []int64{2, 0}, Nulls: []byte{0}, n: 2},
+	}
+	for key, col := range expected {
+		if !reflect.DeepEqual(cols[key], col) {
+			t.Errorf("%s: expected %+v, got %+v", key, col, cols[key])
+		}
+	}
+	// NaN isn't equal to itself
+	value := cols["value"]
+	if value.Len() != 2 || !math.IsNaN(value.Floats[0]) || value.Floats[1] != 1.5 || value.IsNull(0) || value.IsNull(1) {
+		t.Errorf("unexpected value column: %+v", value)
+	}
+	if !cols["count"].IsNull(1) || cols["count"].IsNull(0) {
+		t.Errorf("expected only the second count to be NULL: %+v", cols["count"])
+	}
+}
+
+func TestMarshalColumnsValuers(t *testing.T) {
+	type Bill struct {
+		Dues  nullCents     `csv:"dues"`
+		Count sql.NullInt32 `csv:"count"`
+		Addr  *Address
+	}
+	data := []Bill{
+		Bill{Dues: nullCents{1250, true}, Count: sql.NullInt32{Int32: 3, Valid: true}, Addr: &Address{City: "x"}},
+		Bill{},
+	}
+	cols, err := New().MarshalColumns(data)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	expected := map[string]Column{
+		"dues":  {Index: 0, Kind: reflect.Struct, Strings: []string{"12.50", ""}, Nulls: []byte{2}, n: 2},
+		"count": {Index: 1, Kind: reflect.Int32, Ints: []int64{3, 0}, Nulls: []byte{2}, n: 2},
+		"City":  {Index: 4, Kind: reflect.String, Strings: []string{"x", ""}, Nulls: []byte{2}, n: 2},
+	}
+	for key, col := range expected {
+		if !reflect.DeepEqual(cols[key], col) {
+			t.Errorf("%s: expected %+v, got %+v", key, col, cols[key])
+		}
+	}
+}
+
+func TestMarshalColumnsErrors(t *testing.T) {
+	enc := centsEncoder()
+	enc.SetErrorPolicy(SkipOnError)
+	cols, err := enc.MarshalColumns(orders)
+	var fe *FieldError
+	if !errors.As(err, &fe) {
+		t.Fatalf("expected a *FieldError, got %v", err)
+	}
+	// the header is row 0
+	if fe.Row != 2 {
+		t.Errorf("expected row 2, got %d", fe.Row)
+	}
+	if cols["ID"].Len() != 2 {
+		t.Errorf("expected 2 rows, got %d", cols["ID"].Len())
+	}
+
+	// only the fields that couldn't be encoded get the placeholder, even
+	// if other fields have the same value
+	enc = centsEncoder()
+	enc.SetErrorPolicy(PlaceholderOnError)
+	enc.SetPlaceholder("0")
+	bad := []Order{Order{ID: 0, Amount: -1}}
+	cols, err = enc.MarshalColumns(bad)
+	if !errors.Is(err, errNegative) {
+		t.Errorf("expected error to wrap %q, got %v", errNegative, err)
+	}
+	id := cols["ID"]
+	if !reflect.DeepEqual(id.Ints, []int64{0}) || id.IsNull(0) {
+		t.Errorf("expected ID 0, got %v, NULL: %t", id.Ints, id.IsNull(0))
+	}
+	if amount := cols["amount"]; !reflect.DeepEqual(amount.Strings, []string{"0"}) {
+		t.Errorf("expected the placeholder for amount, got %q", amount.Strings)
+	}
+	_, rows, err := enc.typedData(bad)
+	if !errors.Is(err, errNegative) {
+		t.Errorf("expected error to wrap %q, got %v", errNegative, err)
+	}
+	if len(rows) != 1 || rows[0][0] != int64(0) || rows[0][1] != "0" {
+		t.Errorf("expected ID 0 and the placeholder for amount, got %#v", rows)
+	}
+}
@@ -0,0 +1,228 @@
+package struct2csv
+
+import "reflect"
+
+// HeaderStyle is the style of the header rows written by a Writer.
+type HeaderStyle int
+
+const (
+	// HeaderFlat is a single header row of column names.  This is the
+	// default.
+	HeaderFlat HeaderStyle = iota
+	// HeaderGrouped is one header row per level of struct nesting.  The
+	// column names are in the last row; each row above holds the names of
+	// the struct fields that the columns are nested in.  A struct field's
+	// name is only in the first of its columns; the rest are empty, which
+	// spreadsheets display as the name spanning the struct's columns.
+	// Embedded structs are not groups, as their fields are promoted.
+	HeaderGrouped
+)
+
+// HeaderMode is what the header is made of: column names or labels.
+type HeaderMode int
+
+const (
+	// HeaderNames uses the column names for the header.  This is the
+	// default.
+	HeaderNames HeaderMode = iota
+	// HeaderLabels uses the column labels for the header; columns without
+	// a label use their name.
+	HeaderLabels
+)
+
+// column is a column in the encoded data.
+type column struct {
+	name        string
+	label       string
+	kind        reflect.Kind // the field's Kind, ignoring pointers; Invalid for formatted and leaf fields
+	typ         reflect.Type // the field's type
+	opts        fieldOptions // the field's tag options
+	groups      []string     // the names of the structs the column is nested in, outermost first
+	groupLabels []string     // the labels of the structs the column is nested in
+	index       []int        // the index sequence of the field, for fieldByIndex
+	optional    bool         // whether the column is nested in a struct pointer, which may be nil
+}
+
+// columns returns the columns for a struct of type typ.  The groups and
+// groupLabels are the names and labels of the struct fields typ is nested in.
+func (e *Encoder) columns(typ reflect.Type, groups, groupLabels []string) []column {
+	var cols []column
+	for i := 0; i < typ.NumField(); i++ {
+		// skip unexported
+		tF := typ.Field(i)
+		if len(tF.PkgPath) > 0 {
+			continue
+		}
+		name, opts := e.getFieldName(tF)
+		if name == "" {
+			continue
+		}
+		label := opts.label
+		if label == "" {
+			label = name
+		}
+		if opts.formatter == "" && !e.isLeaf(tF.Type) {
+			st, isPtr := tF.Type, false
+			if st.Kind() == reflect.Ptr {
+				st, isPtr = e.ptrStruct(st)
+			}
+			switch {
+			case isPtr && e.cyclic(st):
+				// the columns would depend on the data, so it's a
+				// single column
+			case st != nil && st.Kind() == reflect.Struct:
+				g, gl := groups, groupLabels
+				if !tF.Anonymous {
+					g = append(g[:len(g):len(g)], name)
+					gl = append(gl[:len(gl):len(gl)], label)
+				}
+				for _, col := range e.columns(st, g, gl) {
+					col.index = append([]int{i}, col.index...)
+					col.optional = col.optional || isPtr
+					cols = append(cols, col)
+				}
+				continue
+			case !supportedBaseType(tF.Type):
+				continue
+			}
+		}
+		col := column{
+			name: name, label: label, groups: groups, groupLabels: groupLabels,
+			typ: tF.Type, opts: opts, index: []int{i},
+		}
+		if opts.formatter == "" && !e.isLeaf(tF.Type) {
+			col.kind = ptrKind(tF.Type)
+		}
+		cols = append(cols, col)
+	}
+	return cols
+}
+
+// ptrStruct returns the struct type that typ, a pointer, points to, through
+// any number of pointers, if the pointer is encoded as the struct's columns;
+// otherwise nil is returned.
+func (e *Encoder) ptrStruct(typ reflect.Type) (reflect.Type, bool) {
+	for typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+	if typ.Kind() != reflect.Struct || e.isLeaf(typ) {
+		return nil, false
+	}
+	return typ, true
+}
+
+// cyclic returns whether or not the struct type typ has columns nested in a
+// pointer to typ, directly or through other structs.  The columns of such a
+// pointer can't be known without the data, so it is encoded as a single
+// column.
+func (e *Encoder) cyclic(typ reflect.Type) bool {
+	return e.reaches(typ, typ, map[reflect.Type]bool{})
+}
+
+// reaches returns whether or not the columns of the struct type from include
+// a pointer to the struct type to.
+func (e *Encoder) reaches(from, to reflect.Type, seen map[reflect.Type]bool) bool {
+	if seen[from] {
+		return false
+	}
+	seen[from] = true
+	for i := 0; i < from.NumField(); i++ {
+		tF := from.Field(i)
+		if len(tF.PkgPath) > 0 || e.isLeaf(tF.Type) {
+			continue
+		}
+		if name, opts := e.getFieldName(tF); name == "" || opts.formatter != "" {
+			continue
+		}
+		st := tF.Type
+		if st.Kind() == reflect.Ptr {
+			st, _ = e.ptrStruct(st)
+			if st == to {
+				return true
+			}
+		}
+		if st != nil && st.Kind() == reflect.Struct && e.reaches(st, to, seen) {
+			return true
+		}
+	}
+	return false
+}
+
+// fieldByIndex returns the nested field of val, a struct, with the index
+// sequence, following pointers to structs.  False is returned if one of
+// them is nil.
+func fieldByIndex(val reflect.Value, index []int) (reflect.Value, bool) {
+	for i, x := range index {
+		if i > 0 {
+			for val.Kind() == reflect.Ptr {
+				if val.IsNil() {
+					return reflect.Value{}, false
+				}
+				val = val.Elem()
+			}
+		}
+		val = val.Field(x)
+	}
+	return val, true
+}
+
+// numeric returns whether or not the column holds numbers.
+func (c column) numeric() bool {
+	switch c.kind {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return true
+	}
+	return false
+}
+
+// headerRows returns the header rows for the columns using the style and
+// mode.
+func headerRows(cols []column, style HeaderStyle, mode HeaderMode) [][]string {
+	depth := 0
+	if style == HeaderGrouped {
+		for _, col := range cols {
+			if len(col.groups) > depth {
+				depth = len(col.groups)
+			}
+		}
+	}
+	rows := make([][]string, depth+1)
+	for i := range rows {
+		rows[i] = make([]string, len(cols))
+	}
+	for i, col := range cols {
+		rows[depth][i] = col.name
+		groups := col.groups
+		if mode == HeaderLabels {
+			rows[depth][i] = col.label
+			groups = col.groupLabels
+		}
+		if style != HeaderGrouped {
+			continue
+		}
+		for l, g := range groups {
+			// a group's name is only in its first column
+			if i > 0 && sameGroups(cols[i-1].groups, col.groups, l) {
+				continue
+			}
+			rows[l][i] = g
+		}
+	}
+	return rows
+}
+
+// sameGroups returns whether or not a and b share the same groups up to, and
+// including, level l.
+func sameGroups(a, b []string, l int) bool {
+	if len(a) <= l || len(b) <= l {
+		return false
+	}
+	for i := 0; i <= l; i++ {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
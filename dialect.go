@@ -0,0 +1,314 @@
+// Copyright 2015 Joel Scoble. All rights reserved.
+// Use of thsi source code is governed by the MIT license that can
+// be found in the LICENSE file.
+
+// Some aspects of the code are written by The Go Authors.
+// Original comment:
+// Copyright 2011 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package struct2csv
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// A Dialect describes how records are written: the field delimiter, the
+// line terminator, and how fields containing special characters are quoted
+// or escaped.
+//
+// If Quote is not 0, fields containing the delimiter, the quote character,
+// or a line break are quoted; quote characters within a quoted field are
+// doubled, unless Escape is not 0, in which case they are preceded by the
+// Escape character.
+//
+// If Quote is 0, fields are never quoted.  Instead, the delimiter, line
+// breaks, tabs, and the Escape character are escaped using the Escape
+// character, e.g. a newline is written as `\n`.  If Escape is also 0, a
+// field that needs escaping results in ErrNoEscape.
+//
+// Null is written, as is, for NULL values: nil pointers and Valuers with
+// nil values.  Other fields equal to Null are quoted, or, without a quote
+// character, have their Escape characters escaped, e.g. the string `\N` is
+// written as `\\N`.
+type Dialect struct {
+	Comma   rune   // field delimiter
+	UseCRLF bool   // use \r\n as the line terminator
+	BOM     bool   // write a UTF-8 byte order mark before the first record
+	Quote   rune   // quote character, 0 for no quoting
+	Escape  rune   // escape character, 0 for none
+	Null    string // the NULL token
+}
+
+// Predefined Dialects.
+var (
+	// DefaultDialect is what encoding/csv writes; this is the Writer's
+	// default.
+	DefaultDialect = Dialect{Comma: ',', Quote: '"'}
+	// RFC4180 is CSV as described by RFC 4180.
+	RFC4180 = Dialect{Comma: ',', UseCRLF: true, Quote: '"'}
+	// Excel is CSV that Excel will open as UTF-8.
+	Excel = Dialect{Comma: ',', UseCRLF: true, BOM: true, Quote: '"'}
+	// TSV is tab separated values, with tabs, line breaks and backslashes
+	// escaped using a backslash.
+	TSV = Dialect{Comma: '\t', Escape: '\\'}
+	// PostgresCOPY is the text format used by PostgreSQL's COPY.
+	PostgresCOPY = Dialect{Comma: '\t', Escape: '\\', Null: `\N`}
+	// MySQL is the format used by MySQL's LOAD DATA INFILE with its default
+	// options.
+	MySQL = Dialect{Comma: '\t', Escape: '\\', Null: `\N`}
+)
+
+// QuotePolicy is when fields are quoted.  It only applies to Dialects with
+// a quote character.
+type QuotePolicy int
+
+const (
+	// QuoteMinimal only quotes fields that contain special characters.
+	// This is the default.
+	QuoteMinimal QuotePolicy = iota
+	// QuoteAll quotes every field, except NULL values.
+	QuoteAll
+	// QuoteNonNumeric quotes every field except those in numeric columns,
+	// i.e. columns holding ints, uints and floats, which are quoted only
+	// if necessary.  Header rows, and records written with Write, have no
+	// numeric columns.
+	QuoteNonNumeric
+	// QuoteNone never quotes fields.  Special characters, including the
+	// Dialect's quote character, are escaped using its Escape character
+	// instead, as they are by Dialects without a quote character; without
+	// an Escape character, a field with special characters results in
+	// ErrNoEscape.
+	QuoteNone
+)
+
+var errInvalidDelim = errors.New("struct2csv: invalid field delimiter")
+
+// ErrNoEscape occurs when a field that isn't quoted contains a character
+// that must be escaped, e.g. the delimiter, but there isn't an escape
+// character.  The record isn't written.
+var ErrNoEscape = errors.New("struct2csv: field needs escaping, but there is no escape character")
+
+// recordWriter writes records using a Dialect.  It is based on
+// encoding/csv's Writer, which doesn't support escaping, and has the same
+// methods.
+type recordWriter struct {
+	Dialect
+	quoting QuotePolicy
+	w       *bufio.Writer
+	bomDone bool // whether the BOM has been written, or shouldn't be
+}
+
+func newRecordWriter(w io.Writer) *recordWriter {
+	return &recordWriter{Dialect: DefaultDialect, w: bufio.NewWriter(w)}
+}
+
+// Write writes a single record, with any necessary quoting or escaping.
+func (w *recordWriter) Write(record []string) error {
+	return w.writeRecord(record, nil, nil)
+}
+
+// writeRecord writes a single record.  If numeric isn't nil, it reports
+// which of the fields are in numeric columns.  If nulls isn't nil, it
+// reports which of the fields are NULL; they are written as is.
+func (w *recordWriter) writeRecord(record []string, numeric, nulls []bool) error {
+	if !validDelim(w.Comma) || w.Comma == w.Quote || w.Comma == w.Escape {
+		return errInvalidDelim
+	}
+	if w.Escape == 0 && (w.Quote == 0 || w.quoting == QuoteNone) {
+		for n, field := range record {
+			if (n >= len(nulls) || !nulls[n]) && w.needsEscape(field) {
+				return ErrNoEscape
+			}
+		}
+	}
+	if w.BOM && !w.bomDone {
+		if _, err := w.w.WriteRune('\uFEFF'); err != nil {
+			return err
+		}
+	}
+	w.bomDone = true
+
+	for n, field := range record {
+		if n > 0 {
+			if _, err := w.w.WriteRune(w.Comma); err != nil {
+				return err
+			}
+		}
+		var err error
+		switch {
+		case n < len(nulls) && nulls[n]:
+			_, err = w.w.WriteString(w.Null)
+		case w.Quote == 0 || w.quoting == QuoteNone:
+			err = w.writeEscaped(field)
+		case w.quoting == QuoteAll,
+			w.quoting == QuoteNonNumeric && (n >= len(numeric) || !numeric[n]),
+			w.fieldNeedsQuotes(field):
+			err = w.writeQuoted(field)
+		default:
+			_, err = w.w.WriteString(field)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	var err error
+	if w.UseCRLF {
+		_, err = w.w.WriteString("\r\n")
+	} else {
+		err = w.w.WriteByte('\n')
+	}
+	return err
+}
+
+// writeQuoted writes field enclosed in quote characters.
+func (w *recordWriter) writeQuoted(field string) error {
+	if _, err := w.w.WriteRune(w.Quote); err != nil {
+		return err
+	}
+	for _, r := range field {
+		var err error
+		switch {
+		case r == w.Quote && w.Escape != 0:
+			_, err = w.w.WriteRune(w.Escape)
+			if err == nil {
+				_, err = w.w.WriteRune(r)
+			}
+		case r == w.Quote:
+			_, err = w.w.WriteRune(r)
+			if err == nil {
+				_, err = w.w.WriteRune(r)
+			}
+		case r == w.Escape && w.Escape != 0:
+			_, err = w.w.WriteRune(r)
+			if err == nil {
+				_, err = w.w.WriteRune(r)
+			}
+		case r == '\r':
+			if !w.UseCRLF {
+				err = w.w.WriteByte('\r')
+			}
+		case r == '\n':
+			if w.UseCRLF {
+				_, err = w.w.WriteString("\r\n")
+			} else {
+				err = w.w.WriteByte('\n')
+			}
+		default:
+			_, err = w.w.WriteRune(r)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	_, err := w.w.WriteRune(w.Quote)
+	return err
+}
+
+// writeEscaped writes field, escaping the delimiter, line breaks, tabs, the
+// escape character, and the quote character, so that readers of the dialect
+// don't treat it as quoting.
+func (w *recordWriter) writeEscaped(field string) error {
+	for _, r := range field {
+		esc := w.escaped(r)
+		var err error
+		if esc == 0 {
+			_, err = w.w.WriteRune(r)
+		} else {
+			_, err = w.w.WriteRune(w.Escape)
+			if err == nil {
+				_, err = w.w.WriteRune(esc)
+			}
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// escaped returns the rune written after the escape character for r, or 0
+// if r isn't escaped.
+func (w *recordWriter) escaped(r rune) rune {
+	switch r {
+	case '\n':
+		return 'n'
+	case '\r':
+		return 'r'
+	case '\t':
+		// without an escape character, tabs are only special as the
+		// delimiter
+		if w.Escape == 0 && w.Comma != '\t' {
+			return 0
+		}
+		return 't'
+	case w.Escape, w.Comma, w.Quote:
+		return r
+	}
+	return 0
+}
+
+// needsEscape reports whether field has runes that must be escaped when it
+// isn't quoted.
+func (w *recordWriter) needsEscape(field string) bool {
+	for _, r := range field {
+		if w.escaped(r) != 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// fieldNeedsQuotes reports whether our field must be enclosed in quotes.
+// Fields with a Comma, fields with a quote or newline, and
+// fields which start with a space must be enclosed in quotes.
+// An empty field is not quoted, to distinguish it from a field
+// containing an empty string.  The field `\.` is always quoted, as it
+// marks the end of data for PostgreSQL, as are fields equal to the NULL
+// token, which aren't NULL.
+func (w *recordWriter) fieldNeedsQuotes(field string) bool {
+	if field == "" {
+		return false
+	}
+	if field == `\.` || field == w.Null {
+		return true
+	}
+	if strings.ContainsRune(field, w.Comma) || strings.ContainsRune(field, w.Quote) || strings.ContainsAny(field, "\r\n") {
+		return true
+	}
+	r1, _ := utf8.DecodeRuneInString(field)
+	return unicode.IsSpace(r1)
+}
+
+// Flush writes any buffered data to the underlying io.Writer.
+func (w *recordWriter) Flush() {
+	w.w.Flush()
+}
+
+// Error reports any error that has occurred during a previous Write or
+// Flush.
+func (w *recordWriter) Error() error {
+	_, err := w.w.Write(nil)
+	return err
+}
+
+// WriteAll writes multiple records using Write and then calls Flush.
+func (w *recordWriter) WriteAll(records [][]string) error {
+	for _, record := range records {
+		err := w.Write(record)
+		if err != nil {
+			return err
+		}
+	}
+	return w.w.Flush()
+}
+
+func validDelim(r rune) bool {
+	return r != 0 && r != '\r' && r != '\n' && utf8.ValidRune(r) && r != utf8.RuneError
+}
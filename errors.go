@@ -0,0 +1,154 @@
+package struct2csv
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// ErrorPolicy determines what happens when a struct can't be encoded.
+type ErrorPolicy int
+
+const (
+	// AbortOnError stops encoding at the first error, which is returned.
+	// This is the default.
+	AbortOnError ErrorPolicy = iota
+	// SkipOnError skips structs that can't be encoded and continues with
+	// the next one.  All errors are collected and returned as a MultiError.
+	SkipOnError
+	// PlaceholderOnError encodes the fields that couldn't be encoded using
+	// the placeholder value and continues.  All errors are collected and
+	// returned as a MultiError.
+	PlaceholderOnError
+)
+
+// A FieldError is returned when a field's value can't be encoded, e.g. a
+// registered EncodeFunc returned an error.  It wraps the original error,
+// which can be checked with errors.Is and errors.As.
+type FieldError struct {
+	// Row is the index of the row in the output, with the header being row
+	// 0, or -1 if it isn't known.
+	Row int
+	// Index is the position of the struct in the input, starting at 0: its
+	// index in the slice being encoded or, for writers, the number of
+	// structs the writer was given before it.  It is -1 if it isn't known.
+	// Unlike Row, it differs for each struct, even when structs are skipped.
+	Index int
+	// Path is the path to the value that couldn't be encoded, e.g.
+	// Location.Address.Zip or Lines[2].
+	Path string
+	// Type is the type of the field.
+	Type reflect.Type
+	Err  error
+}
+
+func (e *FieldError) Error() string {
+	if e.Row < 0 {
+		return fmt.Sprintf("struct2csv: field %s (%s): %s", e.Path, e.Type, e.Err)
+	}
+	return fmt.Sprintf("struct2csv: row %d: field %s (%s): %s", e.Row, e.Path, e.Type, e.Err)
+}
+
+// Unwrap returns the underlying error.
+func (e *FieldError) Unwrap() error {
+	return e.Err
+}
+
+// A MultiError is a collection of errors; it is returned when encoding
+// continued after errors because of the ErrorPolicy.  errors.Is and
+// errors.As check each of its errors.
+type MultiError []error
+
+func (e MultiError) Error() string {
+	switch len(e) {
+	case 0:
+		return "struct2csv: no errors"
+	case 1:
+		return e[0].Error()
+	}
+	return fmt.Sprintf("%s (and %d other errors)", e[0], len(e)-1)
+}
+
+// Unwrap returns the errors.
+func (e MultiError) Unwrap() []error {
+	return e
+}
+
+// Is reports whether any of the errors matches target.  Go versions before
+// 1.20 don't use Unwrap() []error, so errors.Is relies on this.
+func (e MultiError) Is(target error) bool {
+	for _, err := range e {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// As finds the first of the errors that matches target and, if one does,
+// sets target to it and returns true.  Go versions before 1.20 don't use
+// Unwrap() []error, so errors.As relies on this.
+func (e MultiError) As(target interface{}) bool {
+	for _, err := range e {
+		if errors.As(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// fieldError adds the field, or element, name to the path of err, which
+// becomes a *FieldError if it isn't already.  Element names, e.g. [2], are
+// prepended to the path without a separator.  If err is a MultiError, the
+// name is added to each of its errors.
+func fieldError(err error, name string, typ reflect.Type) error {
+	switch fe := err.(type) {
+	case *FieldError:
+		if strings.HasPrefix(fe.Path, "[") {
+			fe.Path = name + fe.Path
+		} else {
+			fe.Path = name + "." + fe.Path
+		}
+		return fe
+	case MultiError:
+		for i := range fe {
+			fe[i] = fieldError(fe[i], name, typ)
+		}
+		return fe
+	}
+	return &FieldError{Row: -1, Index: -1, Path: name, Type: typ, Err: err}
+}
+
+// rowError sets the input index and output row of err, if it is a
+// *FieldError, or of each of its errors, if it is a MultiError.
+func rowError(err error, index, row int) error {
+	switch fe := err.(type) {
+	case *FieldError:
+		fe.Index, fe.Row = index, row
+	case MultiError:
+		for _, err := range fe {
+			rowError(err, index, row)
+		}
+	}
+	return err
+}
+
+// appendErrors appends err to errs; if err is a MultiError, each of its
+// errors is appended.
+func appendErrors(errs MultiError, err error) MultiError {
+	if me, ok := err.(MultiError); ok {
+		return append(errs, me...)
+	}
+	return append(errs, err)
+}
+
+// isEncodingError returns whether or not err is from encoding a field, as
+// opposed to writing the output.
+func isEncodingError(err error) bool {
+	switch err.(type) {
+	case *FieldError, MultiError:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,199 @@
+package struct2csv
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+type Order struct {
+	ID     int
+	Amount int64 `csv:"amount,fmt=cents"`
+	Ship   Shipping
+}
+
+type Shipping struct {
+	Carrier string
+	Cost    int64 `csv:"cost,fmt=cents"`
+}
+
+var errNegative = errors.New("negative amount")
+
+func centsEncoder() *Encoder {
+	enc := New()
+	enc.RegisterFormatter("cents", func(v reflect.Value) (string, error) {
+		c := v.Int()
+		if c < 0 {
+			return "", errNegative
+		}
+		return fmt.Sprintf("%d.%02d", c/100, c%100), nil
+	})
+	return enc
+}
+
+var orders = []Order{
+	Order{ID: 1, Amount: 100, Ship: Shipping{"UPS", 10}},
+	Order{ID: 2, Amount: -100, Ship: Shipping{"DHL", -5}},
+	Order{ID: 3, Amount: 250, Ship: Shipping{"FedEx", 15}},
+}
+
+func TestErrorPolicy(t *testing.T) {
+	tsts := []struct {
+		policy   ErrorPolicy
+		expected [][]string
+		paths    []string
+	}{
+		{AbortOnError, nil, []string{"Amount"}},
+		{SkipOnError, [][]string{
+			[]string{"ID", "amount", "Carrier", "cost"},
+			[]string{"1", "1.00", "UPS", "0.10"},
+			[]string{"3", "2.50", "FedEx", "0.15"},
+		}, []string{"Amount"}},
+		{PlaceholderOnError, [][]string{
+			[]string{"ID", "amount", "Carrier", "cost"},
+			[]string{"1", "1.00", "UPS", "0.10"},
+			[]string{"2", "#ERR", "DHL", "#ERR"},
+			[]string{"3", "2.50", "FedEx", "0.15"},
+		}, []string{"Amount", "Ship.Cost"}},
+	}
+	for i, tst := range tsts {
+		enc := centsEncoder()
+		enc.SetErrorPolicy(tst.policy)
+		enc.SetPlaceholder("#ERR")
+		rows, err := enc.Marshal(orders)
+		if !reflect.DeepEqual(rows, tst.expected) {
+			t.Errorf("%d: expected %q, got %q", i, tst.expected, rows)
+		}
+		if !errors.Is(err, errNegative) {
+			t.Errorf("%d: expected error to wrap %q, got %v", i, errNegative, err)
+			continue
+		}
+		var errs []error
+		if me, ok := err.(MultiError); ok {
+			errs = me
+		} else {
+			errs = []error{err}
+		}
+		if len(errs) != len(tst.paths) {
+			t.Errorf("%d: expected %d errors, got %d: %v", i, len(tst.paths), len(errs), errs)
+			continue
+		}
+		for j, err := range errs {
+			fe, ok := err.(*FieldError)
+			if !ok {
+				t.Errorf("%d:%d: expected a *FieldError, got %T", i, j, err)
+				continue
+			}
+			if fe.Row != 2 || fe.Path != tst.paths[j] {
+				t.Errorf("%d:%d: expected row 2 and path %q, got %d and %q", i, j, tst.paths[j], fe.Row, fe.Path)
+			}
+		}
+	}
+}
+
+func TestWriterOnError(t *testing.T) {
+	buff := &bytes.Buffer{}
+	w := NewWriter(buff)
+	w.e = *centsEncoder()
+	var quarantined []int
+	w.SetOnError(func(row int, err error) error {
+		quarantined = append(quarantined, row)
+		return nil
+	})
+	err := w.WriteStructs(orders)
+	if err != nil {
+		t.Errorf("unexpected error: %s", err)
+	}
+	expected := "ID,amount,Carrier,cost\n1,1.00,UPS,0.10\n3,2.50,FedEx,0.15\n"
+	if buff.String() != expected {
+		t.Errorf("expected %q, got %q", expected, buff.String())
+	}
+	if !reflect.DeepEqual(quarantined, []int{1}) {
+		t.Errorf("expected order 1 to be quarantined, got %v", quarantined)
+	}
+
+	// returning an error stops the writing
+	buff.Reset()
+	w = NewWriter(buff)
+	w.e = *centsEncoder()
+	errStop := errors.New("stop")
+	w.SetOnError(func(row int, err error) error { return errStop })
+	err = w.WriteStructs(orders)
+	if err != errStop {
+		t.Errorf("expected %q, got %v", errStop, err)
+	}
+	expected = "ID,amount,Carrier,cost\n1,1.00,UPS,0.10\n"
+	if buff.String() != expected {
+		t.Errorf("expected %q, got %q", expected, buff.String())
+	}
+}
+
+// Adjacent bad structs have the same Row, as skipped structs aren't
+// output, but different Indexes.
+func TestErrorIndex(t *testing.T) {
+	bad := []Order{
+		Order{ID: 1, Amount: -1},
+		Order{ID: 2, Amount: -2},
+		Order{ID: 3, Amount: 3},
+	}
+	enc := centsEncoder()
+	enc.SetErrorPolicy(SkipOnError)
+	_, err := enc.Marshal(bad)
+	me, ok := err.(MultiError)
+	if !ok || len(me) != 2 {
+		t.Fatalf("expected a MultiError of 2 errors, got %v", err)
+	}
+	for i, err := range me {
+		fe := err.(*FieldError)
+		if fe.Index != i || fe.Row != 1 {
+			t.Errorf("%d: expected index %d and row 1, got %d and %d", i, i, fe.Index, fe.Row)
+		}
+	}
+
+	// a Writer's index counts the structs it has been given
+	w := NewWriter(&bytes.Buffer{})
+	w.e = *centsEncoder()
+	var quarantined []int
+	w.SetOnError(func(i int, err error) error {
+		var fe *FieldError
+		if !errors.As(err, &fe) || fe.Index != i {
+			t.Errorf("expected a *FieldError with index %d, got %v", i, err)
+		}
+		quarantined = append(quarantined, i)
+		return nil
+	})
+	err = w.WriteStructs(bad)
+	if err != nil {
+		t.Errorf("unexpected error: %s", err)
+	}
+	err = w.WriteStruct(bad[0])
+	if err != nil {
+		t.Errorf("unexpected error: %s", err)
+	}
+	if !reflect.DeepEqual(quarantined, []int{0, 1, 3}) {
+		t.Errorf("expected structs 0, 1, and 3 to be quarantined, got %v", quarantined)
+	}
+}
+
+// Before Go 1.20, errors.Is and errors.As don't use Unwrap() []error, so
+// MultiError's Is and As methods are tested directly.
+func TestMultiErrorIsAs(t *testing.T) {
+	errOther := errors.New("other")
+	me := MultiError{errOther, &FieldError{Row: 2, Path: "Amount", Err: errNegative}}
+	if !me.Is(errNegative) || !me.Is(errOther) {
+		t.Errorf("expected %v to match both errors", me)
+	}
+	if me.Is(errors.New("negative amount")) {
+		t.Errorf("expected %v not to match a different error", me)
+	}
+	var fe *FieldError
+	if !me.As(&fe) || fe.Row != 2 {
+		t.Errorf("expected the *FieldError, got %v", fe)
+	}
+	var se StructRequiredError
+	if me.As(&se) {
+		t.Errorf("expected no StructRequiredError, got %v", se)
+	}
+}
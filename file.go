@@ -0,0 +1,131 @@
+package struct2csv
+
+import (
+	"compress/gzip"
+	"io"
+	"os"
+	"strings"
+)
+
+// A Compressor compresses data written to a file by a FileWriter.
+type Compressor interface {
+	// NewWriter returns an io.WriteCloser that compresses the data written
+	// to it and writes it to w.  Closing it must flush any buffered data,
+	// but not close w.
+	NewWriter(w io.Writer) (io.WriteCloser, error)
+}
+
+// GzipCompressor is a Compressor that uses gzip.  Level is the compression
+// level; 0 uses gzip.DefaultCompression.
+type GzipCompressor struct {
+	Level int
+}
+
+// NewWriter returns a new gzip.Writer that writes to w.
+func (c GzipCompressor) NewWriter(w io.Writer) (io.WriteCloser, error) {
+	level := c.Level
+	if level == 0 {
+		level = gzip.DefaultCompression
+	}
+	return gzip.NewWriterLevel(w, level)
+}
+
+// fileConfig is the configuration of a FileWriter.
+type fileConfig struct {
+	compressors map[string]Compressor // by file extension
+	compressor  Compressor            // used regardless of extension
+}
+
+// A FileOption configures a FileWriter.
+type FileOption func(*fileConfig)
+
+// WithCompressor sets the Compressor used for files whose names end with ext,
+// e.g. ".zst".  By default, ".gz" files are compressed using gzip.
+func WithCompressor(ext string, c Compressor) FileOption {
+	return func(cfg *fileConfig) {
+		if cfg.compressors == nil {
+			cfg.compressors = make(map[string]Compressor)
+		}
+		cfg.compressors[strings.ToLower(ext)] = c
+	}
+}
+
+// WithCompression sets the Compressor used, regardless of the file's
+// extension.  A nil Compressor results in no compression.
+func WithCompression(c Compressor) FileOption {
+	return func(cfg *fileConfig) {
+		cfg.compressor = c
+		cfg.compressors = map[string]Compressor{}
+	}
+}
+
+// A FileWriter is a Writer that writes to a file, compressing it depending
+// on the file's extension.  Close must be called when done.
+type FileWriter struct {
+	*Writer
+	f *os.File
+	c io.WriteCloser // the compressor, if any
+}
+
+// NewFileWriter creates the file at path, truncating it if it exists, and
+// returns a FileWriter that writes to it.  If the path ends with an extension
+// that has a Compressor, e.g. "data.csv.gz", the file is compressed.
+func NewFileWriter(path string, opts ...FileOption) (*FileWriter, error) {
+	cfg := fileConfig{compressors: map[string]Compressor{".gz": GzipCompressor{}}}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	c := cfg.compressor
+	if c == nil {
+		c = cfg.compressorFor(path)
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	fw := &FileWriter{f: f}
+	var w io.Writer = f
+	if c != nil {
+		fw.c, err = c.NewWriter(f)
+		if err != nil {
+			f.Close()
+			return nil, err
+		}
+		w = fw.c
+	}
+	fw.Writer = NewWriter(w)
+	return fw, nil
+}
+
+// compressorFor returns the Compressor for the longest extension that path
+// ends with, or nil if there isn't one.
+func (cfg *fileConfig) compressorFor(path string) Compressor {
+	path = strings.ToLower(path)
+	var c Compressor
+	var n int
+	for ext, comp := range cfg.compressors {
+		if len(ext) > n && strings.HasSuffix(path, ext) {
+			c, n = comp, len(ext)
+		}
+	}
+	return c
+}
+
+// Close flushes the Writer, closes the compressor, if any, which flushes it,
+// and closes the file, in that order.  The first error that occurred is
+// returned; the file is closed regardless.
+func (w *FileWriter) Close() error {
+	w.Flush()
+	err := w.Error()
+	if w.c != nil {
+		cerr := w.c.Close()
+		if err == nil {
+			err = cerr
+		}
+	}
+	ferr := w.f.Close()
+	if err == nil {
+		err = ferr
+	}
+	return err
+}
@@ -0,0 +1,88 @@
+package struct2csv
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// upperCompressor "compresses" by upper casing ASCII letters.
+type upperCompressor struct {
+	closeErr error
+}
+
+func (c upperCompressor) NewWriter(w io.Writer) (io.WriteCloser, error) {
+	return &upperWriter{w: w, closeErr: c.closeErr}, nil
+}
+
+type upperWriter struct {
+	w        io.Writer
+	closeErr error
+}
+
+func (u *upperWriter) Write(p []byte) (int, error) {
+	return u.w.Write(bytes.ToUpper(p))
+}
+
+func (u *upperWriter) Close() error {
+	return u.closeErr
+}
+
+func TestFileWriter(t *testing.T) {
+	dir := t.TempDir()
+	data := []Basic{Basic{Name: "Leo Tolstoy", List: []string{"War and Peace"}}}
+	expected := "Nom,Liste\nLeo Tolstoy,War and Peace\n"
+	errClose := errors.New("close failed")
+	tsts := []struct {
+		name     string
+		opts     []FileOption
+		expected string
+		err      error
+	}{
+		{"data.csv", nil, expected, nil},
+		{"data.CSV.GZ", nil, expected, nil}, // decompressed below
+		{"data.csv.up", []FileOption{WithCompressor(".up", upperCompressor{})}, "NOM,LISTE\nLEO TOLSTOY,WAR AND PEACE\n", nil},
+		{"data.gz", []FileOption{WithCompression(nil)}, expected, nil},
+		{"later.csv.up", []FileOption{WithCompression(nil), WithCompressor(".up", upperCompressor{})}, "NOM,LISTE\nLEO TOLSTOY,WAR AND PEACE\n", nil},
+		{"bad.csv", []FileOption{WithCompression(upperCompressor{closeErr: errClose})}, "NOM,LISTE\nLEO TOLSTOY,WAR AND PEACE\n", errClose},
+	}
+	for _, test := range tsts {
+		path := filepath.Join(dir, test.name)
+		w, err := NewFileWriter(path, test.opts...)
+		if err != nil {
+			t.Errorf("%s: unexpected error: %s", test.name, err)
+			continue
+		}
+		err = w.WriteStructs(data)
+		if err != nil {
+			t.Errorf("%s: unexpected error: %s", test.name, err)
+		}
+		err = w.Close()
+		if !errors.Is(err, test.err) {
+			t.Errorf("%s: expected error %v, got %v", test.name, test.err, err)
+		}
+		b, err := os.ReadFile(path)
+		if err != nil {
+			t.Errorf("%s: unexpected error: %s", test.name, err)
+			continue
+		}
+		if test.name == "data.CSV.GZ" {
+			r, err := gzip.NewReader(bytes.NewReader(b))
+			if err != nil {
+				t.Errorf("%s: unexpected error: %s", test.name, err)
+				continue
+			}
+			b, err = io.ReadAll(r)
+			if err != nil {
+				t.Errorf("%s: unexpected error: %s", test.name, err)
+			}
+		}
+		if string(b) != test.expected {
+			t.Errorf("%s: expected %q, got %q", test.name, test.expected, b)
+		}
+	}
+}
@@ -0,0 +1,537 @@
+package struct2csv
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+	"unicode/utf8"
+)
+
+// Align is the alignment of a field within its width.
+type Align int
+
+const (
+	// AlignAuto right aligns numbers and left aligns everything else.  This
+	// is the default.
+	AlignAuto Align = iota
+	// AlignLeft aligns the field to the left.
+	AlignLeft
+	// AlignRight aligns the field to the right.
+	AlignRight
+)
+
+// OverflowPolicy is what happens when a field is wider than its column.
+type OverflowPolicy int
+
+const (
+	// ErrorOnOverflow returns a WidthOverflowError.  This is the default.
+	ErrorOnOverflow OverflowPolicy = iota
+	// TruncateOverflow truncates the field to the width of its column.
+	TruncateOverflow
+)
+
+// A WidthOverflowError is returned when a field is wider than its column
+// and the OverflowPolicy is ErrorOnOverflow.
+type WidthOverflowError struct {
+	Row    int
+	Column string
+	Width  int
+	Value  string
+}
+
+func (e WidthOverflowError) Error() string {
+	return fmt.Sprintf("struct2csv: row %d: column %q: %q is wider than %d", e.Row, e.Column, e.Value, e.Width)
+}
+
+// A MissingWidthError is returned when a column doesn't have a width.
+type MissingWidthError struct {
+	Column string
+}
+
+func (e MissingWidthError) Error() string {
+	return fmt.Sprintf("struct2csv: column %q has no width", e.Column)
+}
+
+// A FieldCountError is returned when a record doesn't have the same number
+// of fields as there are columns.
+type FieldCountError struct {
+	Expected int
+	Got      int
+}
+
+func (e FieldCountError) Error() string {
+	return fmt.Sprintf("struct2csv: expected %d fields, got %d", e.Expected, e.Got)
+}
+
+// A FixedColumn is the layout of a column of fixed-width records.
+type FixedColumn struct {
+	Name  string
+	Width int
+	Align Align
+	Pad   rune // the rune fields are padded with
+}
+
+// A FixedWidthWriter writes structs as fixed-width records.  The columns
+// are the same as those of an Encoder; their widths, alignment, and padding
+// come from the width, align, and pad tag options:
+//
+//	type Account struct {
+//		ID      int    `csv:"id,width=8,pad=0"`
+//		Name    string `csv:"name,width=20"`
+//		Balance int    `csv:"balance,width=10,align=left"`
+//	}
+//
+// Fields are left aligned and padded with spaces, except numbers, which are
+// right aligned.  Every column needs a width, either from its tag or from
+// SetWidths.
+type FixedWidthWriter struct {
+	e        Encoder
+	w        *bufio.Writer
+	r        int
+	n        int // the number of structs the FixedWidthWriter has been given
+	widths   []int
+	overflow OverflowPolicy
+	useCRLF  bool
+	writeHdr bool // whether WriteStructs writes the header
+	hdrDone  bool // whether the header has been written
+	typ      reflect.Type
+	layout   []FixedColumn // the layout of typ's columns
+}
+
+// NewFixedWidthWriter returns a new FixedWidthWriter that writes to w.
+func NewFixedWidthWriter(w io.Writer) *FixedWidthWriter {
+	return &FixedWidthWriter{e: *New(), w: bufio.NewWriter(w)}
+}
+
+// SetWidths sets the widths of the columns, in order; these take precedence
+// over the widths set by tags.  A width of 0 means the tag's width is used.
+func (w *FixedWidthWriter) SetWidths(widths ...int) {
+	w.widths = widths
+	w.typ = nil
+}
+
+// Widths returns the widths of the columns of st, a struct.  These can be
+// used to read the data back with a FixedWidthReader.
+func (w *FixedWidthWriter) Widths(st interface{}) ([]int, error) {
+	if reflect.TypeOf(st).Kind() != reflect.Struct {
+		return nil, StructRequiredError{reflect.TypeOf(st).Kind()}
+	}
+	err := w.setLayout(reflect.TypeOf(st))
+	if err != nil {
+		return nil, err
+	}
+	widths := make([]int, len(w.layout))
+	for i, col := range w.layout {
+		widths[i] = col.Width
+	}
+	return widths, nil
+}
+
+// Layout returns the layout of the columns of st, a struct: their names,
+// widths, alignment, and padding.  This can be used to read the data back
+// with a FixedWidthReader, using SetLayout.
+func (w *FixedWidthWriter) Layout(st interface{}) ([]FixedColumn, error) {
+	if reflect.TypeOf(st).Kind() != reflect.Struct {
+		return nil, StructRequiredError{reflect.TypeOf(st).Kind()}
+	}
+	err := w.setLayout(reflect.TypeOf(st))
+	if err != nil {
+		return nil, err
+	}
+	return append([]FixedColumn(nil), w.layout...), nil
+}
+
+// SetOverflowPolicy sets what happens when a field is wider than its
+// column.
+func (w *FixedWidthWriter) SetOverflowPolicy(p OverflowPolicy) {
+	w.overflow = p
+}
+
+// SetWriteHeader sets whether or not WriteStructs writes the column names as
+// the first row.  By default, this is false.  Column names that are wider
+// than their column are truncated.
+func (w *FixedWidthWriter) SetWriteHeader(b bool) {
+	w.writeHdr = b
+}
+
+// SetUseCRLF sets whether or not \r\n is used as the line terminator.
+func (w *FixedWidthWriter) SetUseCRLF(b bool) {
+	w.useCRLF = b
+}
+
+// setLayout sets the layout to that of the columns of typ.
+func (w *FixedWidthWriter) setLayout(typ reflect.Type) error {
+	if typ == w.typ {
+		return nil
+	}
+	cols := w.e.columns(typ, nil, nil)
+	layout := make([]FixedColumn, len(cols))
+	for i, col := range cols {
+		fc := FixedColumn{Name: col.name, Width: col.opts.width, Align: col.opts.align, Pad: col.opts.pad}
+		if i < len(w.widths) && w.widths[i] > 0 {
+			fc.Width = w.widths[i]
+		}
+		if fc.Width == 0 {
+			return MissingWidthError{Column: col.name}
+		}
+		if fc.Align == AlignAuto {
+			fc.Align = AlignLeft
+			if col.numeric() {
+				fc.Align = AlignRight
+			}
+		}
+		if fc.Pad == 0 {
+			fc.Pad = ' '
+		}
+		layout[i] = fc
+	}
+	w.typ, w.layout = typ, layout
+	return nil
+}
+
+// WriteColNames writes out the column names, aligned like their columns and
+// padded with spaces.  Names wider than their column are truncated.
+func (w *FixedWidthWriter) WriteColNames(st interface{}) error {
+	cols, err := w.e.GetColNames(st)
+	if err != nil {
+		return err
+	}
+	err = w.setLayout(reflect.TypeOf(st))
+	if err != nil {
+		return err
+	}
+	err = w.write(cols, true)
+	if err != nil {
+		return err
+	}
+	w.hdrDone = true
+	return nil
+}
+
+// WriteStruct takes a struct, encodes it, and writes it as a fixed-width
+// record.  Encoding errors are handled as they are by Writer.WriteStruct,
+// without an OnError func.
+func (w *FixedWidthWriter) WriteStruct(st interface{}) error {
+	row, err := w.e.GetRow(st)
+	if err != nil {
+		err = rowError(err, w.n, w.r)
+	}
+	w.n++
+	if row == nil {
+		return err
+	}
+	lerr := w.setLayout(reflect.TypeOf(st))
+	if lerr != nil {
+		return lerr
+	}
+	werr := w.write(row, false)
+	if werr != nil {
+		return werr
+	}
+	return err
+}
+
+// WriteStructs takes a slice of structs and writes them as fixed-width
+// records, preceded by the column names if SetWriteHeader(true) was called.
+// When done, Flush is called.  Encoding errors are handled as they are by
+// Writer.WriteStructs, without an OnError func.
+func (w *FixedWidthWriter) WriteStructs(st interface{}) error {
+	val, err := structSlice(st)
+	if err != nil {
+		return err
+	}
+	if w.writeHdr && !w.hdrDone {
+		err = w.WriteColNames(val.Index(0).Interface())
+		if err != nil {
+			return err
+		}
+	}
+	var errs MultiError
+	for i := 0; i < val.Len(); i++ {
+		v := val.Index(i).Interface()
+		index := w.n
+		w.n++
+		row, err := w.e.GetRow(v)
+		if err != nil {
+			err = rowError(err, index, w.r)
+			if w.e.policy == AbortOnError {
+				w.Flush()
+				return err
+			}
+			errs = appendErrors(errs, err)
+		}
+		if row == nil {
+			continue
+		}
+		err = w.setLayout(reflect.TypeOf(v))
+		if err == nil {
+			err = w.write(row, false)
+		}
+		if err != nil {
+			w.Flush()
+			return err
+		}
+	}
+	w.Flush()
+	err = w.Error()
+	if err != nil {
+		return err
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// Write writes row as a fixed-width record using the layout of the last
+// struct written, or the widths set by SetWidths if no struct has been
+// written.
+func (w *FixedWidthWriter) Write(row []string) error {
+	if w.typ == nil {
+		w.layout = w.layout[:0]
+		for _, width := range w.widths {
+			w.layout = append(w.layout, FixedColumn{Width: width, Align: AlignLeft, Pad: ' '})
+		}
+	}
+	return w.write(row, false)
+}
+
+// write writes the row using the current layout.  If hdr is true, the row is
+// a header: fields that are too wide are always truncated and padding is
+// always spaces.
+func (w *FixedWidthWriter) write(row []string, hdr bool) error {
+	if len(row) != len(w.layout) {
+		return FieldCountError{Expected: len(w.layout), Got: len(row)}
+	}
+	var b strings.Builder
+	for i, col := range w.layout {
+		field := row[i]
+		n := utf8.RuneCountInString(field)
+		if n > col.Width {
+			if !hdr && w.overflow == ErrorOnOverflow {
+				return WidthOverflowError{Row: w.r, Column: col.Name, Width: col.Width, Value: field}
+			}
+			field = truncate(field, col.Width)
+			n = col.Width
+		}
+		pad := col.Pad
+		if hdr {
+			pad = ' '
+		}
+		padding := strings.Repeat(string(pad), col.Width-n)
+		if col.Align == AlignLeft {
+			b.WriteString(field)
+			b.WriteString(padding)
+			continue
+		}
+		// keep the sign in front of zero padding
+		if pad == '0' && len(field) > 0 && (field[0] == '-' || field[0] == '+') {
+			b.WriteByte(field[0])
+			field = field[1:]
+		}
+		b.WriteString(padding)
+		b.WriteString(field)
+	}
+	if w.useCRLF {
+		b.WriteString("\r\n")
+	} else {
+		b.WriteByte('\n')
+	}
+	_, err := w.w.WriteString(b.String())
+	if err != nil {
+		return err
+	}
+	w.r++
+	return nil
+}
+
+// truncate returns the first n runes of s.
+func truncate(s string, n int) string {
+	for i := range s {
+		if n == 0 {
+			return s[:i]
+		}
+		n--
+	}
+	return s
+}
+
+// Flush writes any buffered data to the underlying io.Writer.
+func (w *FixedWidthWriter) Flush() {
+	w.w.Flush()
+}
+
+// Error reports an error that has occurred during a previous Write or Flush
+func (w *FixedWidthWriter) Error() error {
+	_, err := w.w.Write(nil)
+	return err
+}
+
+// Rows returns the number of rows written. This includes the header row.
+func (w *FixedWidthWriter) Rows() int {
+	return w.r
+}
+
+// Expose Encoder methods
+
+// SetTag set's the tag value to match on for a struct's field tags.
+func (w *FixedWidthWriter) SetTag(s string) {
+	w.e.SetTag(s)
+	w.typ = nil
+}
+
+// SetTags set's the tags, in order of precedence, to match on for a struct's
+// field tags.
+func (w *FixedWidthWriter) SetTags(tags ...string) {
+	w.e.SetTags(tags...)
+	w.typ = nil
+}
+
+// SetNameMapper sets the func used to transform field names into column
+// names for fields whose name doesn't come from a tag.
+func (w *FixedWidthWriter) SetNameMapper(fn func(string) string) {
+	w.e.SetNameMapper(fn)
+	w.typ = nil
+}
+
+// SetBase set's the base for both signed and unsigned integer values.
+func (w *FixedWidthWriter) SetBase(i int) {
+	w.e.SetBase(i)
+}
+
+// SetFloatFormat sets the format and precision used for float values.
+func (w *FixedWidthWriter) SetFloatFormat(fmt byte, prec int) {
+	w.e.SetFloatFormat(fmt, prec)
+}
+
+// SetNullToken sets the value used for nil pointers and NULL values.
+func (w *FixedWidthWriter) SetNullToken(s string) {
+	w.e.SetNullToken(s)
+}
+
+// RegisterType registers fn as the encoder for values of type typ.
+func (w *FixedWidthWriter) RegisterType(typ reflect.Type, fn EncodeFunc) {
+	w.e.RegisterType(typ, fn)
+	w.typ = nil
+}
+
+// RegisterFormatter registers fn as the formatter called name.
+func (w *FixedWidthWriter) RegisterFormatter(name string, fn EncodeFunc) {
+	w.e.RegisterFormatter(name, fn)
+}
+
+// SetErrorPolicy sets what happens when a struct can't be encoded.
+func (w *FixedWidthWriter) SetErrorPolicy(p ErrorPolicy) {
+	w.e.SetErrorPolicy(p)
+}
+
+// SetPlaceholder sets the value used for fields that can't be encoded when
+// the ErrorPolicy is PlaceholderOnError.
+func (w *FixedWidthWriter) SetPlaceholder(s string) {
+	w.e.SetPlaceholder(s)
+}
+
+// A FixedWidthReader reads fixed-width records, like those written by a
+// FixedWidthWriter, splitting them into fields using the column widths.
+// Lines shorter than the layout are treated as if they were padded;
+// anything past the last column is ignored.
+//
+// By default, spaces around fields are removed.  With the layout of the
+// FixedWidthWriter, set using SetLayout, only the padding is removed: the
+// pad rune on the side opposite the alignment, after the sign of zero
+// padded fields.  A value that starts, when right aligned, or ends, when
+// left aligned, with the pad rune can't be told apart from the padding; a
+// zero padded field of only zeros is read as "0".
+type FixedWidthReader struct {
+	s      *bufio.Scanner
+	layout []FixedColumn
+}
+
+// NewFixedWidthReader returns a new FixedWidthReader that reads from r using
+// the column widths.
+func NewFixedWidthReader(r io.Reader, widths ...int) *FixedWidthReader {
+	layout := make([]FixedColumn, len(widths))
+	for i, width := range widths {
+		layout[i] = FixedColumn{Width: width, Pad: ' '}
+	}
+	return &FixedWidthReader{s: bufio.NewScanner(r), layout: layout}
+}
+
+// SetLayout sets the layout of the columns, e.g. from FixedWidthWriter's
+// Layout, replacing the widths.
+func (r *FixedWidthReader) SetLayout(layout []FixedColumn) {
+	r.layout = layout
+}
+
+// Read reads one record.  At the end of the input, io.EOF is returned.
+func (r *FixedWidthReader) Read() ([]string, error) {
+	return r.read(false)
+}
+
+// ReadHeader reads one record of column names, like those written by
+// FixedWidthWriter's WriteColNames, which are always padded with spaces.
+func (r *FixedWidthReader) ReadHeader() ([]string, error) {
+	return r.read(true)
+}
+
+// read reads one record.  If hdr is true, the record is a header.
+func (r *FixedWidthReader) read(hdr bool) ([]string, error) {
+	if !r.s.Scan() {
+		if err := r.s.Err(); err != nil {
+			return nil, err
+		}
+		return nil, io.EOF
+	}
+	line := strings.TrimSuffix(r.s.Text(), "\r")
+	record := make([]string, len(r.layout))
+	for i, col := range r.layout {
+		field := truncate(line, col.Width)
+		line = line[len(field):]
+		if hdr {
+			record[i] = strings.TrimSpace(field)
+			continue
+		}
+		record[i] = unpad(field, col)
+	}
+	return record, nil
+}
+
+// unpad removes the padding of field in col.
+func unpad(field string, col FixedColumn) string {
+	pad := string(col.Pad)
+	if col.Pad == 0 {
+		pad = " "
+	}
+	switch col.Align {
+	case AlignLeft:
+		return strings.TrimRight(field, pad)
+	case AlignRight:
+		var sign string
+		if pad == "0" && len(field) > 0 && (field[0] == '-' || field[0] == '+') {
+			sign, field = field[:1], field[1:]
+		}
+		field = strings.TrimLeft(field, pad)
+		if pad == "0" && field == "" {
+			field = "0"
+		}
+		return sign + field
+	}
+	return strings.Trim(field, pad)
+}
+
+// ReadAll reads all the remaining records.
+func (r *FixedWidthReader) ReadAll() ([][]string, error) {
+	var records [][]string
+	for {
+		record, err := r.Read()
+		if err == io.EOF {
+			return records, nil
+		}
+		if err != nil {
+			return records, err
+		}
+		records = append(records, record)
+	}
+}
@@ -0,0 +1,159 @@
+package struct2csv
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+type Ledger struct {
+	ID      int     `csv:"id,width=6,pad=0"`
+	Name    string  `csv:"name,width=8"`
+	Balance float64 `csv:"balance,width=9,format=f,prec=2"`
+	Branch  string  `csv:"branch,width=4,align=right"`
+}
+
+func TestFixedWidthWriter(t *testing.T) {
+	data := []Ledger{
+		Ledger{ID: 42, Name: "Ada", Balance: 10.5, Branch: "NY"},
+		Ledger{ID: -7, Name: "Grace", Balance: -3, Branch: "SF"},
+	}
+	buff := &bytes.Buffer{}
+	w := NewFixedWidthWriter(buff)
+	w.SetWriteHeader(true)
+	err := w.WriteStructs(data)
+	if err != nil {
+		t.Errorf("unexpected error: %s", err)
+	}
+	// column names are truncated, and padded with spaces
+	expected := "    idname      balancebran\n" +
+		"000042Ada         10.50  NY\n" +
+		"-00007Grace       -3.00  SF\n"
+	if buff.String() != expected {
+		t.Errorf("expected %q, got %q", expected, buff.String())
+	}
+	if w.Rows() != 3 {
+		t.Errorf("expected 3 rows, got %d", w.Rows())
+	}
+
+	// read it back
+	widths, err := w.Widths(Ledger{})
+	if err != nil {
+		t.Errorf("unexpected error: %s", err)
+	}
+	r := NewFixedWidthReader(bytes.NewReader(buff.Bytes()), widths...)
+	records, err := r.ReadAll()
+	if err != nil {
+		t.Errorf("unexpected error: %s", err)
+	}
+	expectedRecords := [][]string{
+		{"id", "name", "balance", "bran"},
+		{"000042", "Ada", "10.50", "NY"},
+		{"-00007", "Grace", "-3.00", "SF"},
+	}
+	if !reflect.DeepEqual(records, expectedRecords) {
+		t.Errorf("expected %q, got %q", expectedRecords, records)
+	}
+}
+
+func TestFixedWidthOverflow(t *testing.T) {
+	data := []Ledger{Ledger{ID: 1, Name: "Bartholomew", Branch: "LA"}}
+	buff := &bytes.Buffer{}
+	w := NewFixedWidthWriter(buff)
+	err := w.WriteStructs(data)
+	var oe WidthOverflowError
+	if !errors.As(err, &oe) {
+		t.Errorf("expected a WidthOverflowError, got %v", err)
+	} else if oe.Column != "name" || oe.Width != 8 || oe.Row != 0 {
+		t.Errorf("unexpected error: %#v", oe)
+	}
+
+	buff.Reset()
+	w = NewFixedWidthWriter(buff)
+	w.SetOverflowPolicy(TruncateOverflow)
+	err = w.WriteStructs(data)
+	if err != nil {
+		t.Errorf("unexpected error: %s", err)
+	}
+	expected := "000001Bartholo     0.00  LA\n"
+	if buff.String() != expected {
+		t.Errorf("expected %q, got %q", expected, buff.String())
+	}
+
+	// a missing width is an error, unless it's set using SetWidths
+	type Short struct {
+		A string `csv:"a,width=2"`
+		B string
+	}
+	buff.Reset()
+	w = NewFixedWidthWriter(buff)
+	err = w.WriteStruct(Short{A: "x", B: "y"})
+	if _, ok := err.(MissingWidthError); !ok {
+		t.Errorf("expected a MissingWidthError, got %v", err)
+	}
+	w.SetWidths(0, 3)
+	err = w.WriteStruct(Short{A: "x", B: "y"})
+	if err != nil {
+		t.Errorf("unexpected error: %s", err)
+	}
+	w.Flush()
+	if buff.String() != "x y  \n" {
+		t.Errorf("expected %q, got %q", "x y  \n", buff.String())
+	}
+}
+
+func TestFixedWidthReaderLayout(t *testing.T) {
+	type Entry struct {
+		ID   int    `csv:"id,width=5,pad=0"`
+		Code string `csv:"code,width=6,pad=*"`
+		Memo string `csv:"memo,width=6,align=right"`
+	}
+	data := []Entry{
+		Entry{ID: 42, Code: "*A", Memo: "x "},
+		Entry{ID: -7, Code: "B", Memo: "y"},
+		Entry{ID: 0, Code: "", Memo: ""},
+	}
+	buff := &bytes.Buffer{}
+	w := NewFixedWidthWriter(buff)
+	w.SetWriteHeader(true)
+	err := w.WriteStructs(data)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	layout, err := w.Layout(Entry{})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	expectedLayout := []FixedColumn{
+		{Name: "id", Width: 5, Align: AlignRight, Pad: '0'},
+		{Name: "code", Width: 6, Align: AlignLeft, Pad: '*'},
+		{Name: "memo", Width: 6, Align: AlignRight, Pad: ' '},
+	}
+	if !reflect.DeepEqual(layout, expectedLayout) {
+		t.Errorf("expected %+v, got %+v", expectedLayout, layout)
+	}
+	r := NewFixedWidthReader(bytes.NewReader(buff.Bytes()))
+	r.SetLayout(layout)
+	hdr, err := r.ReadHeader()
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if !reflect.DeepEqual(hdr, []string{"id", "code", "memo"}) {
+		t.Errorf("expected the column names, got %q", hdr)
+	}
+	records, err := r.ReadAll()
+	if err != nil {
+		t.Errorf("unexpected error: %s", err)
+	}
+	// only the padding is removed: the code's leading * and the memo's
+	// trailing space are kept
+	expected := [][]string{
+		{"42", "*A", "x "},
+		{"-7", "B", "y"},
+		{"0", "", ""},
+	}
+	if !reflect.DeepEqual(records, expected) {
+		t.Errorf("expected %q, got %q", expected, records)
+	}
+}
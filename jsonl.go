@@ -0,0 +1,169 @@
+package struct2csv
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"io"
+	"reflect"
+)
+
+// A JSONLWriter writes structs as JSON Lines, also known as NDJSON: one JSON
+// object per line.  The objects are the same flattened view of the structs
+// as the Encoder's CSV, with the column names as keys, in column order;
+// names that aren't unique are qualified by the names of the structs they
+// are nested in, e.g. "Home.Street".
+// Numbers, bools, and NULL values are native JSON values, time.Time values
+// are RFC 3339 strings, and everything else is a string encoded as it would
+// be for CSV.
+type JSONLWriter struct {
+	e    *Encoder
+	w    *bufio.Writer
+	r    int
+	n    int // the number of structs the JSONLWriter has been given
+	typ  reflect.Type
+	keys []string // the JSON encoded column names of typ
+	cols []column // the columns of typ
+	buf  bytes.Buffer
+}
+
+// NewJSONLWriter returns a new JSONLWriter that writes to w.
+func NewJSONLWriter(w io.Writer) *JSONLWriter {
+	return &JSONLWriter{e: New(), w: bufio.NewWriter(w)}
+}
+
+// SetEncoder sets the Encoder used to encode structs.
+func (w *JSONLWriter) SetEncoder(e *Encoder) {
+	w.e = e
+	w.typ = nil
+}
+
+// WriteStruct writes st, a struct, as a JSON object.  If a field can't be
+// encoded, the error is returned and, unless the Encoder's ErrorPolicy is
+// PlaceholderOnError, the struct isn't written; a *FieldError's Row is the
+// index of the object that was being written and its Index is the number
+// of structs the JSONLWriter was given before st.
+func (w *JSONLWriter) WriteStruct(st interface{}) error {
+	typ := reflect.TypeOf(st)
+	if typ.Kind() != reflect.Struct {
+		return StructRequiredError{typ.Kind()}
+	}
+	if typ != w.typ {
+		err := w.setColumns(st)
+		if err != nil {
+			return err
+		}
+	}
+	row, err := w.e.typedRow(reflect.ValueOf(st), w.cols)
+	if err != nil {
+		err = rowError(err, w.n, w.r)
+	}
+	w.n++
+	if row == nil {
+		return err
+	}
+	w.buf.Reset()
+	w.buf.WriteByte('{')
+	for i, v := range row {
+		if i > 0 {
+			w.buf.WriteByte(',')
+		}
+		w.buf.WriteString(w.keys[i])
+		w.buf.WriteByte(':')
+		werr := writeJSON(&w.buf, v)
+		if werr != nil {
+			return werr
+		}
+	}
+	w.buf.WriteString("}\n")
+	_, werr := w.w.Write(w.buf.Bytes())
+	if werr != nil {
+		return werr
+	}
+	w.r++
+	return err
+}
+
+// setColumns sets the keys and columns to those of st.  Column names that
+// aren't unique are qualified, as they are by MarshalColumns.
+func (w *JSONLWriter) setColumns(st interface{}) error {
+	cols := w.e.columns(reflect.TypeOf(st), nil, nil)
+	names, err := columnKeys(cols)
+	if err != nil {
+		return err
+	}
+	w.keys = make([]string, len(names))
+	for i, name := range names {
+		var b bytes.Buffer
+		err := writeJSON(&b, name)
+		if err != nil {
+			return err
+		}
+		w.keys[i] = b.String()
+	}
+	w.cols = cols
+	w.typ = reflect.TypeOf(st)
+	return nil
+}
+
+// WriteStructs takes a slice of structs and writes each of them as a JSON
+// object.  When done, Flush is called.  If a field can't be encoded, what
+// happens depends on the Encoder's ErrorPolicy: AbortOnError stops writing
+// and returns the error; the other policies continue writing and return a
+// MultiError of all the errors that occurred.
+func (w *JSONLWriter) WriteStructs(st interface{}) error {
+	val, err := structSlice(st)
+	if err != nil {
+		return err
+	}
+	var errs MultiError
+	for i := 0; i < val.Len(); i++ {
+		err = w.WriteStruct(val.Index(i).Interface())
+		if err == nil {
+			continue
+		}
+		if !isEncodingError(err) || w.e.policy == AbortOnError {
+			w.Flush()
+			return err
+		}
+		errs = appendErrors(errs, err)
+	}
+	w.Flush()
+	err = w.Error()
+	if err != nil {
+		return err
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// Flush writes any buffered data to the underlying io.Writer.
+func (w *JSONLWriter) Flush() {
+	w.w.Flush()
+}
+
+// Error reports an error that has occurred during a previous Write or Flush
+func (w *JSONLWriter) Error() error {
+	_, err := w.w.Write(nil)
+	return err
+}
+
+// Rows returns the number of objects written.
+func (w *JSONLWriter) Rows() int {
+	return w.r
+}
+
+// writeJSON writes v, JSON encoded, to b, without escaping HTML.
+func writeJSON(b *bytes.Buffer, v interface{}) error {
+	enc := json.NewEncoder(b)
+	enc.SetEscapeHTML(false)
+	err := enc.Encode(v)
+	if err != nil {
+		return err
+	}
+	// remove the newline added by Encode
+	b.Truncate(b.Len() - 1)
+	return nil
+}
@@ -0,0 +1,112 @@
+package struct2csv
+
+import (
+	"bytes"
+	"errors"
+	"math"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestJSONLWriter(t *testing.T) {
+	type Event struct {
+		ID    uint64    `csv:"id"`
+		Level string    `csv:"level"`
+		At    time.Time `csv:"at"`
+		Ratio float64   `csv:"ratio,format=f,prec=1"`
+		OK    bool      `csv:"ok"`
+		User  *string   `csv:"user"`
+		Tags  []string  `csv:"tags"`
+		Meta  struct {
+			Host string `csv:"host"`
+		} `csv:"meta"`
+		Skip string `csv:"-"`
+	}
+	e1 := Event{ID: 1, Level: "<warn>", At: time.Date(2015, 11, 22, 10, 30, 0, 0, time.UTC), Ratio: 0.25, OK: true, Tags: []string{"a", "b"}}
+	e1.Meta.Host = "db1"
+	e2 := Event{ID: 2, Ratio: math.NaN()}
+	buff := &bytes.Buffer{}
+	w := NewJSONLWriter(buff)
+	err := w.WriteStructs([]Event{e1, e2})
+	if err != nil {
+		t.Errorf("unexpected error: %s", err)
+	}
+	expected := `{"id":1,"level":"<warn>","at":"2015-11-22T10:30:00Z","ratio":0.25,"ok":true,"user":null,"tags":"a,b","host":"db1"}` + "\n" +
+		`{"id":2,"level":"","at":"0001-01-01T00:00:00Z","ratio":"NaN","ok":false,"user":null,"tags":"","host":""}` + "\n"
+	if buff.String() != expected {
+		t.Errorf("expected %s, got %s", expected, buff.String())
+	}
+	if w.Rows() != 2 {
+		t.Errorf("expected 2 rows, got %d", w.Rows())
+	}
+
+	// the Encoder's configuration is used
+	enc := New()
+	enc.SetNameMapper(SnakeCase)
+	enc.SetUseTags(false)
+	buff.Reset()
+	w = NewJSONLWriter(buff)
+	w.SetEncoder(enc)
+	err = w.WriteStruct(Stock{Symbol: "ABC", Price: 1})
+	if err != nil {
+		t.Errorf("unexpected error: %s", err)
+	}
+	w.Flush()
+	expected = `{"symbol":"ABC","price":1,"note":""}` + "\n"
+	if buff.String() != expected {
+		t.Errorf("expected %s, got %s", expected, buff.String())
+	}
+}
+
+func TestJSONLWriterError(t *testing.T) {
+	errNeg := errors.New("negative")
+	enc := New()
+	enc.RegisterFormatter("cents", func(v reflect.Value) (string, error) {
+		if v.Int() < 0 {
+			return "", errNeg
+		}
+		return "ok", nil
+	})
+	enc.SetErrorPolicy(SkipOnError)
+	buff := &bytes.Buffer{}
+	w := NewJSONLWriter(buff)
+	w.SetEncoder(enc)
+	err := w.WriteStructs([]Refund{Refund{ID: 1, Amount: -1}, Refund{ID: 2, Amount: 1}})
+	var me MultiError
+	if !errors.As(err, &me) || len(me) != 1 {
+		t.Errorf("expected a MultiError with 1 error, got %v", err)
+	}
+	var fe *FieldError
+	if !errors.As(me[0], &fe) || fe.Row != 0 || !errors.Is(fe, errNeg) {
+		t.Errorf("unexpected error: %v", me[0])
+	}
+	if buff.Len() == 0 || bytes.Count(buff.Bytes(), []byte("\n")) != 1 {
+		t.Errorf("expected 1 line, got %q", buff.String())
+	}
+}
+
+func TestJSONLWriterNested(t *testing.T) {
+	type Person struct {
+		Name string
+		Home Address
+		Work *Address
+	}
+	buff := &bytes.Buffer{}
+	w := NewJSONLWriter(buff)
+	err := w.WriteStructs([]Person{
+		Person{Name: "a", Home: Address{City: "x"}, Work: &Address{City: "y"}},
+		Person{Name: "b"},
+	})
+	if err != nil {
+		t.Errorf("unexpected error: %s", err)
+	}
+	expected := `{"Name":"a","Home.Addr1":"","Home.Addr2":"","Home.City":"x","Home.State":"","Home.Zip":"","Work.Addr1":"","Work.Addr2":"","Work.City":"y","Work.State":"","Work.Zip":""}` + "\n" +
+		`{"Name":"b","Home.Addr1":"","Home.Addr2":"","Home.City":"","Home.State":"","Home.Zip":"","Work.Addr1":null,"Work.Addr2":null,"Work.City":null,"Work.State":null,"Work.Zip":null}` + "\n"
+	if buff.String() != expected {
+		t.Errorf("expected %s, got %s", expected, buff.String())
+	}
+	if w.Rows() != 2 {
+		t.Errorf("expected 2 rows, got %d", w.Rows())
+	}
+}
@@ -0,0 +1,135 @@
+package struct2csv
+
+import (
+	"fmt"
+	"strings"
+)
+
+// NumberFormatter formats numbers for output.  FormatNumber receives the
+// number as formatted by strconv, e.g. "-1234.56" or "1.5E+00", and returns
+// the string that should be used instead.  This allows for locale specific
+// decimal separators and digit grouping.
+//
+// NumberFormatters are applied to base 10 integers and to floats; they are
+// not applied to NaN or Inf values, or to numbers formatted using another
+// base.
+type NumberFormatter interface {
+	FormatNumber(s string) string
+}
+
+// BoolFormatter formats bool values for output, e.g. "ja" and "nein" or "1"
+// and "0".
+type BoolFormatter interface {
+	FormatBool(b bool) string
+}
+
+// NumberFormat is a NumberFormatter that uses the specified decimal
+// separator and digit grouping separator.  A zero value for either means
+// that the strconv default is used: a decimal separator of '.' and no digit
+// grouping.  Digits are grouped in threes.
+//
+// For example, NumberFormat{Decimal: ',', Group: '.'} formats 1234.56 as
+// 1.234,56.
+type NumberFormat struct {
+	Decimal rune
+	Group   rune
+}
+
+// FormatNumber implements NumberFormatter.
+func (f NumberFormat) FormatNumber(s string) string {
+	if f.Decimal == 0 && f.Group == 0 {
+		return s
+	}
+	var sign string
+	if len(s) > 0 && (s[0] == '-' || s[0] == '+') {
+		sign, s = s[:1], s[1:]
+	}
+	// the integer part ends at the first non-digit: a decimal point or an
+	// exponent.
+	i := strings.IndexFunc(s, func(r rune) bool { return r < '0' || r > '9' })
+	if i < 0 {
+		i = len(s)
+	}
+	intPart, rest := s[:i], s[i:]
+	if f.Group != 0 && len(intPart) > 3 {
+		var b strings.Builder
+		lead := len(intPart) % 3
+		if lead > 0 {
+			b.WriteString(intPart[:lead])
+		}
+		for j := lead; j < len(intPart); j += 3 {
+			if j > 0 {
+				b.WriteRune(f.Group)
+			}
+			b.WriteString(intPart[j : j+3])
+		}
+		intPart = b.String()
+	}
+	if f.Decimal != 0 && strings.HasPrefix(rest, ".") {
+		rest = string(f.Decimal) + rest[1:]
+	}
+	return sign + intPart + rest
+}
+
+// Separators returns the decimal and digit grouping separators that will be
+// used.
+func (f NumberFormat) Separators() (decimal, group rune) {
+	decimal = f.Decimal
+	if decimal == 0 {
+		decimal = '.'
+	}
+	return decimal, f.Group
+}
+
+// BoolFormat is a BoolFormatter that uses the specified tokens for true and
+// false values.
+type BoolFormat struct {
+	True  string
+	False string
+}
+
+// FormatBool implements BoolFormatter.
+func (f BoolFormat) FormatBool(b bool) string {
+	if b {
+		return f.True
+	}
+	return f.False
+}
+
+// separatorer is implemented by NumberFormatters that can report the
+// separators that they use.  This is used to detect conflicts with the CSV
+// field delimiter.
+type separatorer interface {
+	Separators() (decimal, group rune)
+}
+
+// A SeparatorConflictError is returned when the CSV field delimiter is the
+// same as a separator used for formatting numbers.  Numbers will be quoted,
+// so the CSV is still valid, but many consumers will not be able to handle
+// it.
+type SeparatorConflictError struct {
+	Comma rune
+	Use   string // the separator's use; either decimal or group
+}
+
+func (e SeparatorConflictError) Error() string {
+	return fmt.Sprintf("struct2csv: the field delimiter %q is also the number %s separator", e.Comma, e.Use)
+}
+
+// separatorConflict checks whether or not the field delimiter, comma, is also
+// used as a separator by the NumberFormatter.  If it is, an error is
+// returned.
+func separatorConflict(comma rune, nf NumberFormatter) error {
+	s, ok := nf.(separatorer)
+	if !ok {
+		return nil
+	}
+	dec, grp := s.Separators()
+	if dec == comma {
+		return SeparatorConflictError{Comma: comma, Use: "decimal"}
+	}
+	if grp == comma {
+		return SeparatorConflictError{Comma: comma, Use: "group"}
+	}
+	return nil
+}
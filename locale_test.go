@@ -0,0 +1,128 @@
+package struct2csv
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestNumberFormat(t *testing.T) {
+	tsts := []struct {
+		f        NumberFormat
+		val      string
+		expected string
+	}{
+		{NumberFormat{}, "1234.56", "1234.56"},
+		{NumberFormat{Decimal: ','}, "1234.56", "1234,56"},
+		{NumberFormat{Decimal: ',', Group: '.'}, "1234.56", "1.234,56"},
+		{NumberFormat{Decimal: ',', Group: '.'}, "-1234567.5", "-1.234.567,5"},
+		{NumberFormat{Decimal: ',', Group: '.'}, "123", "123"},
+		{NumberFormat{Decimal: ',', Group: '.'}, "123456", "123.456"},
+		{NumberFormat{Group: ' '}, "1000000", "1 000 000"},
+		{NumberFormat{Decimal: ','}, "1.5E+00", "1,5E+00"},
+		{NumberFormat{Decimal: ',', Group: '.'}, "1.2345e+06", "1,2345e+06"},
+	}
+	for i, tst := range tsts {
+		s := tst.f.FormatNumber(tst.val)
+		if s != tst.expected {
+			t.Errorf("%d: expected %q, got %q", i, tst.expected, s)
+		}
+	}
+}
+
+type Measurement struct {
+	Name  string
+	Count int
+	Total uint
+	Hex   uint8
+	Value float64
+	OK    bool
+}
+
+func TestLocaleEncoding(t *testing.T) {
+	enc := New()
+	enc.SetNumberFormatter(NumberFormat{Decimal: ',', Group: '.'})
+	enc.SetBoolFormatter(BoolFormat{True: "ja", False: "nein"})
+	enc.SetFloatFormat('f', 2)
+	row, err := enc.GetRow(Measurement{"a", -12345, 67890, 10, 1234.5, true})
+	if err != nil {
+		t.Errorf("unexpected error: %s", err)
+		return
+	}
+	expected := []string{"a", "-12.345", "67.890", "10", "1.234,50", "ja"}
+	for i, v := range row {
+		if v != expected[i] {
+			t.Errorf("%d: expected %q, got %q", i, expected[i], v)
+		}
+	}
+	// numbers formatted in other bases are left alone.
+	enc.SetBase(16)
+	row, _ = enc.GetRow(Measurement{"b", 1, 65535, 255, 0.5, false})
+	expected = []string{"b", "1", "ffff", "ff", "0,50", "nein"}
+	for i, v := range row {
+		if v != expected[i] {
+			t.Errorf("%d: expected %q, got %q", i, expected[i], v)
+		}
+	}
+}
+
+func TestCheckDialect(t *testing.T) {
+	w := NewWriter(&bytes.Buffer{})
+	if err := w.CheckDialect(); err != nil {
+		t.Errorf("unexpected error: %s", err)
+	}
+	w.SetNumberFormatter(NumberFormat{Decimal: ','})
+	err := w.CheckDialect()
+	if err == nil {
+		t.Error("expected an error, got none")
+	} else if err.Error() != "struct2csv: the field delimiter ',' is also the number decimal separator" {
+		t.Errorf("unexpected error: %s", err)
+	}
+	w.SetComma(';')
+	if err := w.CheckDialect(); err != nil {
+		t.Errorf("unexpected error: %s", err)
+	}
+	w.SetNumberFormatter(NumberFormat{Decimal: ',', Group: ';'})
+	if _, ok := w.CheckDialect().(SeparatorConflictError); !ok {
+		t.Error("expected a SeparatorConflictError")
+	}
+
+	// by default, a conflict is only a warning; the affected fields are
+	// quoted
+	type Price struct {
+		Amount float64 `csv:"amount,format=f,prec=2"`
+	}
+	buff := &bytes.Buffer{}
+	w = NewWriter(buff)
+	w.SetNumberFormatter(NumberFormat{Decimal: ','})
+	err = w.WriteStructs([]Price{{1.5}})
+	if err != nil {
+		t.Errorf("unexpected error: %s", err)
+	}
+	if buff.String() != "amount\n\"1,50\"\n" {
+		t.Errorf("expected %q, got %q", "amount\n\"1,50\"\n", buff.String())
+	}
+
+	// a strict Writer checks the dialect, whatever order it was set in
+	buff.Reset()
+	w = NewWriter(buff)
+	w.SetNumberFormatter(NumberFormat{Decimal: ','})
+	w.SetStrictDialect(true)
+	if _, ok := w.WriteStructs([]Price{{1.5}}).(SeparatorConflictError); !ok {
+		t.Error("expected a SeparatorConflictError")
+	}
+	if _, ok := w.WriteStruct(Price{1.5}).(SeparatorConflictError); !ok {
+		t.Error("expected a SeparatorConflictError")
+	}
+	w.Flush()
+	if buff.Len() != 0 {
+		t.Errorf("expected nothing to be written, got %q", buff.String())
+	}
+	w.SetComma(';')
+	err = w.WriteStructs([]Price{{1.5}})
+	if err != nil {
+		t.Errorf("unexpected error: %s", err)
+	}
+	if buff.String() != "amount\n1,50\n" {
+		t.Errorf("expected %q, got %q", "amount\n1,50\n", buff.String())
+	}
+}
@@ -0,0 +1,112 @@
+package struct2csv
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// SnakeCase transforms a Go field name into snake case, e.g. CustomerID
+// becomes customer_id.  It can be used with SetNameMapper.
+func SnakeCase(s string) string {
+	return joinWords(splitWords(s), "_", strings.ToLower)
+}
+
+// UpperSnake transforms a Go field name into upper snake case, e.g.
+// CustomerID becomes CUSTOMER_ID.  It can be used with SetNameMapper.
+func UpperSnake(s string) string {
+	return joinWords(splitWords(s), "_", strings.ToUpper)
+}
+
+// KebabCase transforms a Go field name into kebab case, e.g. CustomerID
+// becomes customer-id.  It can be used with SetNameMapper.
+func KebabCase(s string) string {
+	return joinWords(splitWords(s), "-", strings.ToLower)
+}
+
+// LowerCamel transforms a Go field name into lower camel case, e.g.
+// CustomerName becomes customerName.  Acronyms, other than a leading one,
+// keep their case: CustomerID becomes customerID and HTTPServer becomes
+// httpServer.  It can be used with SetNameMapper.
+func LowerCamel(s string) string {
+	words := splitWords(s)
+	for i, w := range words {
+		if i == 0 {
+			words[i] = strings.ToLower(w)
+			continue
+		}
+		if isAcronym(w) {
+			continue
+		}
+		r, n := utf8.DecodeRuneInString(w)
+		words[i] = string(unicode.ToUpper(r)) + strings.ToLower(w[n:])
+	}
+	return strings.Join(words, "")
+}
+
+func joinWords(words []string, sep string, fn func(string) string) string {
+	for i, w := range words {
+		words[i] = fn(w)
+	}
+	return strings.Join(words, sep)
+}
+
+// splitWords splits a Go identifier into its words.  A new word starts at an
+// upper case letter that follows a lower case letter or a digit, or at the
+// last upper case letter of an acronym that is followed by a lower case
+// letter: HTTPServerID is HTTP, Server, and ID.  An acronym followed by a
+// trailing s is a plural acronym: UserIDs is User and IDs.  Digits belong to the word
+// they follow.  Underscores, hyphens, and spaces separate words and are
+// removed.
+func splitWords(s string) []string {
+	var words []string
+	runes := []rune(s)
+	start := 0
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		if r == '_' || r == '-' || r == ' ' {
+			if i > start {
+				words = append(words, string(runes[start:i]))
+			}
+			start = i + 1
+			continue
+		}
+		if i == start || !unicode.IsUpper(r) {
+			continue
+		}
+		prev := runes[i-1]
+		if unicode.IsLower(prev) || unicode.IsDigit(prev) {
+			words = append(words, string(runes[start:i]))
+			start = i
+			continue
+		}
+		// end of an acronym; a trailing s is a plural acronym, e.g. IDs.
+		if unicode.IsUpper(prev) && i+1 < len(runes) && unicode.IsLower(runes[i+1]) && !isPluralS(runes, i+1) {
+			words = append(words, string(runes[start:i]))
+			start = i
+		}
+	}
+	if start < len(runes) {
+		words = append(words, string(runes[start:]))
+	}
+	return words
+}
+
+// isPluralS returns whether or not the rune at i is an s that ends a word.
+func isPluralS(runes []rune, i int) bool {
+	return runes[i] == 's' && (i+1 == len(runes) || !unicode.IsLower(runes[i+1]))
+}
+
+// isAcronym returns whether or not s has no lower case letters, other than
+// a trailing s, e.g. ID and IDs.
+func isAcronym(s string) bool {
+	if len(s) > 2 {
+		s = strings.TrimSuffix(s, "s")
+	}
+	for _, r := range s {
+		if unicode.IsLower(r) {
+			return false
+		}
+	}
+	return true
+}
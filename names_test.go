@@ -0,0 +1,72 @@
+package struct2csv
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestNameMappers(t *testing.T) {
+	tsts := []struct {
+		name       string
+		snake      string
+		upperSnake string
+		kebab      string
+		lowerCamel string
+	}{
+		{"Name", "name", "NAME", "name", "name"},
+		{"CustomerID", "customer_id", "CUSTOMER_ID", "customer-id", "customerID"},
+		{"ID", "id", "ID", "id", "id"},
+		{"HTTPServer", "http_server", "HTTP_SERVER", "http-server", "httpServer"},
+		{"HTTPServerID", "http_server_id", "HTTP_SERVER_ID", "http-server-id", "httpServerID"},
+		{"Addr1", "addr1", "ADDR1", "addr1", "addr1"},
+		{"Base64Data", "base64_data", "BASE64_DATA", "base64-data", "base64Data"},
+		{"UserIDs", "user_ids", "USER_IDS", "user-ids", "userIDs"},
+		{"already_snake", "already_snake", "ALREADY_SNAKE", "already-snake", "alreadySnake"},
+		{"X", "x", "X", "x", "x"},
+		{"NameÜber", "name_über", "NAME_ÜBER", "name-über", "nameÜber"},
+		{"grün_über", "grün_über", "GRÜN_ÜBER", "grün-über", "grünÜber"},
+		{"", "", "", "", ""},
+	}
+	for i, tst := range tsts {
+		if s := SnakeCase(tst.name); s != tst.snake {
+			t.Errorf("%d: SnakeCase(%q): expected %q, got %q", i, tst.name, tst.snake, s)
+		}
+		if s := UpperSnake(tst.name); s != tst.upperSnake {
+			t.Errorf("%d: UpperSnake(%q): expected %q, got %q", i, tst.name, tst.upperSnake, s)
+		}
+		if s := KebabCase(tst.name); s != tst.kebab {
+			t.Errorf("%d: KebabCase(%q): expected %q, got %q", i, tst.name, tst.kebab, s)
+		}
+		if s := LowerCamel(tst.name); s != tst.lowerCamel {
+			t.Errorf("%d: LowerCamel(%q): expected %q, got %q", i, tst.name, tst.lowerCamel, s)
+		}
+	}
+}
+
+type Shipment struct {
+	ShipmentID int `csv:"id"`
+	FromAddr   ShipmentAddr
+	ToAddr     ShipmentAddr `csv:"destination"`
+}
+
+type ShipmentAddr struct {
+	StreetName string
+	ZIPCode    string
+}
+
+func TestNameMapperNested(t *testing.T) {
+	expected := ",from_addr,,destination,\n" +
+		"id,street_name,zip_code,street_name,zip_code\n" +
+		"1,Main St,12345,Elm St,54321\n"
+	buff := &bytes.Buffer{}
+	w := NewWriter(buff)
+	w.SetNameMapper(SnakeCase)
+	w.SetHeaderStyle(HeaderGrouped)
+	err := w.WriteStructs([]Shipment{Shipment{1, ShipmentAddr{"Main St", "12345"}, ShipmentAddr{"Elm St", "54321"}}})
+	if err != nil {
+		t.Errorf("unexpected error: %s", err)
+	}
+	if buff.String() != expected {
+		t.Errorf("expected %q, got %q", expected, buff.String())
+	}
+}
@@ -0,0 +1,115 @@
+package struct2csv
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// An EncodeFunc returns the string representation of v.
+type EncodeFunc func(v reflect.Value) (string, error)
+
+// A TypeRegistry is something that custom type encoders can be registered
+// with; both Encoder and Writer are TypeRegistries.
+type TypeRegistry interface {
+	RegisterType(typ reflect.Type, fn EncodeFunc)
+}
+
+// An UnknownFormatterError is returned when a field's tag references a
+// formatter that hasn't been registered.
+type UnknownFormatterError struct {
+	Name string
+}
+
+func (e UnknownFormatterError) Error() string {
+	return fmt.Sprintf("struct2csv: unknown formatter %q", e.Name)
+}
+
+// RegisterType registers fn as the encoder for values of type typ.  This
+// allows control over how types that can't be modified, e.g. third-party
+// types like decimal.Decimal, are encoded.  Registered types are always
+// encoded as a single column, even if they are structs.  The registered
+// encoder takes precedence over everything else; it is used for struct
+// fields of type typ and for typ values within slices and maps.
+//
+// Registering a nil EncodeFunc removes the type's encoder.
+func (e *Encoder) RegisterType(typ reflect.Type, fn EncodeFunc) {
+	if fn == nil {
+		delete(e.types, typ)
+		return
+	}
+	if e.types == nil {
+		e.types = make(map[reflect.Type]EncodeFunc)
+	}
+	e.types[typ] = fn
+}
+
+// Register registers fn as the encoder for values of type T with r.  This is
+// a convenience wrapper around RegisterType:
+//
+//	struct2csv.Register(enc, func(d decimal.Decimal) (string, error) {
+//		return d.StringFixed(2), nil
+//	})
+func Register[T any](r TypeRegistry, fn func(T) (string, error)) {
+	typ := reflect.TypeOf((*T)(nil)).Elem()
+	r.RegisterType(typ, func(v reflect.Value) (string, error) {
+		return fn(v.Interface().(T))
+	})
+}
+
+// typeEncoder returns the EncodeFunc registered for typ, if there is one.
+func (e *Encoder) typeEncoder(typ reflect.Type) (EncodeFunc, bool) {
+	if len(e.types) == 0 {
+		return nil, false
+	}
+	fn, ok := e.types[typ]
+	return fn, ok
+}
+
+// isLeaf returns whether or not typ, or the type that typ points to, is
+// encoded as a single value regardless of its Kind: it has a registered
+// EncodeFunc, it implements driver.Valuer, or it is time.Time.
+func (e *Encoder) isLeaf(typ reflect.Type) bool {
+	for {
+		if _, ok := e.typeEncoder(typ); ok {
+			return true
+		}
+		if isValuer(typ) || typ == timeType {
+			return true
+		}
+		if typ.Kind() != reflect.Ptr {
+			return false
+		}
+		typ = typ.Elem()
+	}
+}
+
+// RegisterFormatter registers fn as the formatter called name.  Formatters
+// are referenced from a field's tag using the fmt option, e.g.
+// `csv:"amount,fmt=cents"`, and are used to encode that field's value.
+// This provides field level control over encoding, as opposed to the type
+// level control provided by RegisterType.  A field with a formatter is
+// always encoded as a single column; the formatter receives the field's
+// value as is, including nil pointers.
+//
+// Registering a nil EncodeFunc removes the formatter.
+func (e *Encoder) RegisterFormatter(name string, fn EncodeFunc) {
+	if fn == nil {
+		delete(e.formatters, name)
+		return
+	}
+	if e.formatters == nil {
+		e.formatters = make(map[string]EncodeFunc)
+	}
+	e.formatters[name] = fn
+}
+
+// encodeFormatter encodes val using the formatter referenced by the field's
+// tag.  If the formatter hasn't been registered, an UnknownFormatterError is
+// returned.
+func (e *Encoder) encodeFormatter(val reflect.Value, opts fieldOptions) (string, error) {
+	fn, ok := e.formatters[opts.formatter]
+	if !ok {
+		return "", UnknownFormatterError{Name: opts.formatter}
+	}
+	return fn(val)
+}
@@ -0,0 +1,133 @@
+package struct2csv
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+// Money stands in for a third-party type that can't be modified.
+type Money struct {
+	Units int64
+	Cents int64
+}
+
+type Invoice struct {
+	ID      int
+	Total   Money
+	Tax     *Money
+	Lines   []Money
+	ByLabel map[string]Money
+}
+
+func TestRegisterType(t *testing.T) {
+	enc := New()
+	Register(enc, func(m Money) (string, error) {
+		return fmt.Sprintf("%d.%02d", m.Units, m.Cents), nil
+	})
+	invoices := []Invoice{
+		Invoice{ID: 1, Total: Money{10, 5}, Tax: &Money{1, 50},
+			Lines: []Money{Money{4, 0}, Money{6, 5}}, ByLabel: map[string]Money{"a": Money{1, 1}}},
+		Invoice{ID: 2, Total: Money{0, 99}},
+	}
+	expected := [][]string{
+		[]string{"ID", "Total", "Tax", "Lines", "ByLabel"},
+		[]string{"1", "10.05", "1.50", "4.00,6.05", "a:1.01"},
+		[]string{"2", "0.99", "", "", ""},
+	}
+	rows, err := enc.Marshal(invoices)
+	if err != nil {
+		t.Errorf("unexpected error: %s", err)
+		return
+	}
+	if !reflect.DeepEqual(rows, expected) {
+		t.Errorf("expected %q, got %q", expected, rows)
+	}
+	// removing the encoder results in the struct, and the struct pointer,
+	// being flattened.
+	enc.RegisterType(reflect.TypeOf(Money{}), nil)
+	cols, _ := enc.GetColNames(Invoice{})
+	if len(cols) != 7 {
+		t.Errorf("expected 7 columns, got %d: %v", len(cols), cols)
+	}
+}
+
+func TestRegisterTypeError(t *testing.T) {
+	errBad := errors.New("bad money")
+	enc := New()
+	Register(enc, func(m Money) (string, error) {
+		if m.Cents > 99 {
+			return "", errBad
+		}
+		return fmt.Sprint(m.Units), nil
+	})
+	_, err := enc.Marshal([]Invoice{Invoice{Total: Money{1, 10}}, Invoice{Lines: []Money{Money{1, 1}, Money{1, 100}}}})
+	if !errors.Is(err, errBad) {
+		t.Errorf("expected %q, got %v", errBad, err)
+	}
+	var fe *FieldError
+	if !errors.As(err, &fe) {
+		t.Errorf("expected a *FieldError, got %T", err)
+	} else {
+		if fe.Row != 2 {
+			t.Errorf("expected row 2, got %d", fe.Row)
+		}
+		if fe.Path != "Lines[1]" {
+			t.Errorf("expected path %q, got %q", "Lines[1]", fe.Path)
+		}
+		if fe.Type != reflect.TypeOf(Money{}) {
+			t.Errorf("expected type Money, got %s", fe.Type)
+		}
+	}
+	expected := "struct2csv: row 2: field Lines[1] (struct2csv.Money): bad money"
+	if err.Error() != expected {
+		t.Errorf("expected %q, got %q", expected, err)
+	}
+	_, err = enc.GetRow(Invoice{Total: Money{1, 10}})
+	if err != nil {
+		t.Errorf("unexpected error: %s", err)
+	}
+}
+
+type Payment struct {
+	ID     int
+	Amount int64 `csv:"amount,fmt=cents"`
+	Fee    int64 `csv:"fee,fmt=cents"`
+	Total  Money `csv:"total,fmt=money"`
+}
+
+func TestRegisterFormatter(t *testing.T) {
+	enc := New()
+	enc.RegisterFormatter("cents", func(v reflect.Value) (string, error) {
+		c := v.Int()
+		return fmt.Sprintf("%d.%02d", c/100, c%100), nil
+	})
+	cols, err := enc.GetColNames(Payment{})
+	if err != nil {
+		t.Errorf("unexpected error: %s", err)
+	}
+	if !reflect.DeepEqual(cols, []string{"ID", "amount", "fee", "total"}) {
+		t.Errorf("unexpected column names: %v", cols)
+	}
+	_, err = enc.GetRow(Payment{ID: 1, Amount: 1050, Fee: 5})
+	var ufe UnknownFormatterError
+	if !errors.As(err, &ufe) || ufe.Name != "money" {
+		t.Errorf("expected an UnknownFormatterError for money, got %v", err)
+	}
+	expected := `struct2csv: field Total (struct2csv.Money): struct2csv: unknown formatter "money"`
+	if err.Error() != expected {
+		t.Errorf("expected %q, got %q", expected, err)
+	}
+	enc.RegisterFormatter("money", func(v reflect.Value) (string, error) {
+		m := v.Interface().(Money)
+		return fmt.Sprintf("%d.%02d", m.Units, m.Cents), nil
+	})
+	row, err := enc.GetRow(Payment{ID: 1, Amount: 1050, Fee: 5, Total: Money{10, 55}})
+	if err != nil {
+		t.Errorf("unexpected error: %s", err)
+	}
+	if !reflect.DeepEqual(row, []string{"1", "10.50", "0.05", "10.55"}) {
+		t.Errorf("unexpected row: %v", row)
+	}
+}
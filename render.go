@@ -0,0 +1,342 @@
+package struct2csv
+
+import (
+	"html"
+	"io"
+	"reflect"
+	"strings"
+	"unicode/utf8"
+)
+
+// BoxStyle is the style of the borders drawn by a TableWriter.
+type BoxStyle int
+
+const (
+	// BoxASCII draws borders using -, |, and +.  This is the default.
+	BoxASCII BoxStyle = iota
+	// BoxUnicode draws borders using Unicode box drawing characters.
+	BoxUnicode
+)
+
+// box holds the characters used to draw a table's borders; the corners and
+// junctions are ordered left, middle, right.
+type box struct {
+	horizontal, vertical string
+	top, middle, bottom  [3]string
+}
+
+var boxes = [...]box{
+	BoxASCII: {
+		horizontal: "-", vertical: "|",
+		top: [3]string{"+", "+", "+"}, middle: [3]string{"+", "+", "+"}, bottom: [3]string{"+", "+", "+"},
+	},
+	BoxUnicode: {
+		horizontal: "─", vertical: "│",
+		top: [3]string{"┌", "┬", "┐"}, middle: [3]string{"├", "┼", "┤"}, bottom: [3]string{"└", "┴", "┘"},
+	},
+}
+
+// tableData returns the data of v, a slice of structs, encoded by e with the
+// header as the first row, along with the alignment of each column: numbers
+// are right aligned, everything else is left aligned.  If the ErrorPolicy
+// isn't AbortOnError, the data is returned along with any errors.
+func tableData(e *Encoder, v interface{}) ([][]string, []Align, error) {
+	rows, err := e.Marshal(v)
+	if rows == nil {
+		return nil, nil, err
+	}
+	cols := e.columns(reflect.TypeOf(v).Elem(), nil, nil)
+	aligns := make([]Align, len(cols))
+	for i, col := range cols {
+		aligns[i] = AlignLeft
+		if col.numeric() {
+			aligns[i] = AlignRight
+		}
+	}
+	return rows, aligns, err
+}
+
+// cellWidths returns the width, in runes, of the widest cell in each column.
+func cellWidths(rows [][]string) []int {
+	var widths []int
+	for _, row := range rows {
+		for i, cell := range row {
+			if i >= len(widths) {
+				widths = append(widths, 0)
+			}
+			if n := utf8.RuneCountInString(cell); n > widths[i] {
+				widths[i] = n
+			}
+		}
+	}
+	return widths
+}
+
+// alignCell pads s with spaces to width, according to the alignment.
+func alignCell(s string, width int, a Align) string {
+	padding := strings.Repeat(" ", width-utf8.RuneCountInString(s))
+	if a == AlignRight {
+		return padding + s
+	}
+	return s + padding
+}
+
+// alignAt returns the alignment of column i; columns without one are left
+// aligned.
+func alignAt(aligns []Align, i int) Align {
+	if i < len(aligns) && aligns[i] == AlignRight {
+		return AlignRight
+	}
+	return AlignLeft
+}
+
+// escapeRows returns a copy of rows with each cell escaped using fn.
+func escapeRows(rows [][]string, fn func(string) string) [][]string {
+	escaped := make([][]string, len(rows))
+	for i, row := range rows {
+		escaped[i] = make([]string, len(row))
+		for j, cell := range row {
+			escaped[i][j] = fn(cell)
+		}
+	}
+	return escaped
+}
+
+// A MarkdownWriter writes structs as a GitHub Flavored Markdown table.
+type MarkdownWriter struct {
+	e *Encoder
+	w io.Writer
+}
+
+// NewMarkdownWriter returns a new MarkdownWriter that writes to w.
+func NewMarkdownWriter(w io.Writer) *MarkdownWriter {
+	return &MarkdownWriter{e: New(), w: w}
+}
+
+// SetEncoder sets the Encoder used to encode structs.
+func (w *MarkdownWriter) SetEncoder(e *Encoder) {
+	w.e = e
+}
+
+// WriteStructs takes a slice of structs and writes them as a table, with
+// the column names as the header.  Numbers are right aligned.
+func (w *MarkdownWriter) WriteStructs(v interface{}) error {
+	rows, aligns, err := tableData(w.e, v)
+	if rows == nil {
+		return err
+	}
+	werr := w.WriteAll(rows, aligns)
+	if werr != nil {
+		return werr
+	}
+	return err
+}
+
+// WriteAll writes rows as a table; the first row is the header.  The aligns
+// are the alignment of each column; columns without one are left aligned.
+// Pipes and backslashes are escaped and line breaks are written as <br>.
+func (w *MarkdownWriter) WriteAll(rows [][]string, aligns []Align) error {
+	if len(rows) == 0 {
+		return nil
+	}
+	rows = escapeRows(rows, markdownEscaper.Replace)
+	widths := cellWidths(rows)
+	for i := range widths {
+		// the delimiter row needs at least 3 characters
+		if widths[i] < 3 {
+			widths[i] = 3
+		}
+	}
+	var b strings.Builder
+	writeRow := func(row []string) {
+		b.WriteString("|")
+		for i, width := range widths {
+			var cell string
+			if i < len(row) {
+				cell = row[i]
+			}
+			b.WriteString(" ")
+			b.WriteString(alignCell(cell, width, alignAt(aligns, i)))
+			b.WriteString(" |")
+		}
+		b.WriteString("\n")
+	}
+	writeRow(rows[0])
+	b.WriteString("|")
+	for i, width := range widths {
+		b.WriteString(" ")
+		if alignAt(aligns, i) == AlignRight {
+			b.WriteString(strings.Repeat("-", width-1) + ":")
+		} else {
+			b.WriteString(strings.Repeat("-", width))
+		}
+		b.WriteString(" |")
+	}
+	b.WriteString("\n")
+	for _, row := range rows[1:] {
+		writeRow(row)
+	}
+	_, err := io.WriteString(w.w, b.String())
+	return err
+}
+
+var markdownEscaper = strings.NewReplacer(`\`, `\\`, "|", `\|`, "\r\n", "<br>", "\n", "<br>", "\r", "<br>")
+
+// A TableWriter writes structs as a text table with aligned columns, with
+// borders drawn using ASCII or Unicode box drawing characters.  Widths are
+// measured in runes, so wide characters, e.g. CJK, will misalign columns.
+type TableWriter struct {
+	e     *Encoder
+	w     io.Writer
+	style BoxStyle
+}
+
+// NewTableWriter returns a new TableWriter that writes to w.
+func NewTableWriter(w io.Writer) *TableWriter {
+	return &TableWriter{e: New(), w: w}
+}
+
+// SetEncoder sets the Encoder used to encode structs.
+func (w *TableWriter) SetEncoder(e *Encoder) {
+	w.e = e
+}
+
+// SetBoxStyle sets the style of the borders.
+func (w *TableWriter) SetBoxStyle(s BoxStyle) {
+	w.style = s
+}
+
+// WriteStructs takes a slice of structs and writes them as a table, with
+// the column names as the header.  Numbers are right aligned.
+func (w *TableWriter) WriteStructs(v interface{}) error {
+	rows, aligns, err := tableData(w.e, v)
+	if rows == nil {
+		return err
+	}
+	werr := w.WriteAll(rows, aligns)
+	if werr != nil {
+		return werr
+	}
+	return err
+}
+
+// WriteAll writes rows as a table; the first row is the header.  The aligns
+// are the alignment of each column; columns without one are left aligned.
+// Tabs and line breaks are written as \t, \r, and \n.
+func (w *TableWriter) WriteAll(rows [][]string, aligns []Align) error {
+	if len(rows) == 0 {
+		return nil
+	}
+	rows = escapeRows(rows, tableEscaper.Replace)
+	widths := cellWidths(rows)
+	bx := boxes[BoxASCII]
+	if w.style == BoxUnicode {
+		bx = boxes[BoxUnicode]
+	}
+	var b strings.Builder
+	writeLine := func(junctions [3]string) {
+		b.WriteString(junctions[0])
+		for i, width := range widths {
+			if i > 0 {
+				b.WriteString(junctions[1])
+			}
+			b.WriteString(strings.Repeat(bx.horizontal, width+2))
+		}
+		b.WriteString(junctions[2])
+		b.WriteString("\n")
+	}
+	writeRow := func(row []string, header bool) {
+		b.WriteString(bx.vertical)
+		for i, width := range widths {
+			var cell string
+			if i < len(row) {
+				cell = row[i]
+			}
+			a := alignAt(aligns, i)
+			if header {
+				a = AlignLeft
+			}
+			b.WriteString(" ")
+			b.WriteString(alignCell(cell, width, a))
+			b.WriteString(" ")
+			b.WriteString(bx.vertical)
+		}
+		b.WriteString("\n")
+	}
+	writeLine(bx.top)
+	writeRow(rows[0], true)
+	writeLine(bx.middle)
+	for _, row := range rows[1:] {
+		writeRow(row, false)
+	}
+	writeLine(bx.bottom)
+	_, err := io.WriteString(w.w, b.String())
+	return err
+}
+
+var tableEscaper = strings.NewReplacer("\t", `\t`, "\r", `\r`, "\n", `\n`)
+
+// An HTMLTableWriter writes structs as an HTML table.
+type HTMLTableWriter struct {
+	e *Encoder
+	w io.Writer
+}
+
+// NewHTMLTableWriter returns a new HTMLTableWriter that writes to w.
+func NewHTMLTableWriter(w io.Writer) *HTMLTableWriter {
+	return &HTMLTableWriter{e: New(), w: w}
+}
+
+// SetEncoder sets the Encoder used to encode structs.
+func (w *HTMLTableWriter) SetEncoder(e *Encoder) {
+	w.e = e
+}
+
+// WriteStructs takes a slice of structs and writes them as a table, with
+// the column names as the header.  Numbers are right aligned.
+func (w *HTMLTableWriter) WriteStructs(v interface{}) error {
+	rows, aligns, err := tableData(w.e, v)
+	if rows == nil {
+		return err
+	}
+	werr := w.WriteAll(rows, aligns)
+	if werr != nil {
+		return werr
+	}
+	return err
+}
+
+// WriteAll writes rows as a table; the first row is the header.  The aligns
+// are the alignment of each column; columns without one are left aligned.
+// Cells are HTML escaped and line breaks are written as <br>.
+func (w *HTMLTableWriter) WriteAll(rows [][]string, aligns []Align) error {
+	if len(rows) == 0 {
+		return nil
+	}
+	rows = escapeRows(rows, func(s string) string {
+		return htmlBreaks.Replace(html.EscapeString(s))
+	})
+	var b strings.Builder
+	writeRow := func(row []string, tag string) {
+		b.WriteString("<tr>")
+		for i, cell := range row {
+			b.WriteString("<" + tag)
+			if alignAt(aligns, i) == AlignRight {
+				b.WriteString(` style="text-align: right"`)
+			}
+			b.WriteString(">" + cell + "</" + tag + ">")
+		}
+		b.WriteString("</tr>\n")
+	}
+	b.WriteString("<table>\n<thead>\n")
+	writeRow(rows[0], "th")
+	b.WriteString("</thead>\n<tbody>\n")
+	for _, row := range rows[1:] {
+		writeRow(row, "td")
+	}
+	b.WriteString("</tbody>\n</table>\n")
+	_, err := io.WriteString(w.w, b.String())
+	return err
+}
+
+var htmlBreaks = strings.NewReplacer("\r\n", "<br>", "\n", "<br>", "\r", "<br>")
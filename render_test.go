@@ -0,0 +1,83 @@
+package struct2csv
+
+import (
+	"bytes"
+	"testing"
+)
+
+type Stock struct {
+	Symbol string  `csv:"symbol"`
+	Price  float64 `csv:"price,format=f,prec=2"`
+	Note   string  `csv:"note"`
+}
+
+var stocks = []Stock{
+	Stock{Symbol: "ABC", Price: 12.5, Note: "a|b"},
+	Stock{Symbol: "XY", Price: 105, Note: "<new>\nline"},
+}
+
+func TestMarkdownWriter(t *testing.T) {
+	expected := "| symbol |  price | note          |\n" +
+		"| ------ | -----: | ------------- |\n" +
+		"| ABC    |  12.50 | a\\|b          |\n" +
+		"| XY     | 105.00 | <new><br>line |\n"
+	buff := &bytes.Buffer{}
+	w := NewMarkdownWriter(buff)
+	err := w.WriteStructs(stocks)
+	if err != nil {
+		t.Errorf("unexpected error: %s", err)
+	}
+	if buff.String() != expected {
+		t.Errorf("expected %q, got %q", expected, buff.String())
+	}
+}
+
+func TestTableWriter(t *testing.T) {
+	tsts := []struct {
+		style    BoxStyle
+		expected string
+	}{
+		{BoxASCII, "+--------+--------+-------------+\n" +
+			"| symbol | price  | note        |\n" +
+			"+--------+--------+-------------+\n" +
+			"| ABC    |  12.50 | a|b         |\n" +
+			"| XY     | 105.00 | <new>\\nline |\n" +
+			"+--------+--------+-------------+\n"},
+		{BoxUnicode, "┌────────┬────────┬─────────────┐\n" +
+			"│ symbol │ price  │ note        │\n" +
+			"├────────┼────────┼─────────────┤\n" +
+			"│ ABC    │  12.50 │ a|b         │\n" +
+			"│ XY     │ 105.00 │ <new>\\nline │\n" +
+			"└────────┴────────┴─────────────┘\n"},
+	}
+	for i, test := range tsts {
+		buff := &bytes.Buffer{}
+		w := NewTableWriter(buff)
+		w.SetBoxStyle(test.style)
+		err := w.WriteStructs(stocks)
+		if err != nil {
+			t.Errorf("%d: unexpected error: %s", i, err)
+		}
+		if buff.String() != test.expected {
+			t.Errorf("%d: expected %q, got %q", i, test.expected, buff.String())
+		}
+	}
+}
+
+func TestHTMLTableWriter(t *testing.T) {
+	expected := "<table>\n<thead>\n" +
+		"<tr><th>symbol</th><th style=\"text-align: right\">price</th><th>note</th></tr>\n" +
+		"</thead>\n<tbody>\n" +
+		"<tr><td>ABC</td><td style=\"text-align: right\">12.50</td><td>a|b</td></tr>\n" +
+		"<tr><td>XY</td><td style=\"text-align: right\">105.00</td><td>&lt;new&gt;<br>line</td></tr>\n" +
+		"</tbody>\n</table>\n"
+	buff := &bytes.Buffer{}
+	w := NewHTMLTableWriter(buff)
+	err := w.WriteStructs(stocks)
+	if err != nil {
+		t.Errorf("unexpected error: %s", err)
+	}
+	if buff.String() != expected {
+		t.Errorf("expected %q, got %q", expected, buff.String())
+	}
+}
@@ -0,0 +1,183 @@
+package struct2csv
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// ErrFileTemplate occurs when a RotatingWriter's file name template doesn't
+// produce a different name for each file.
+var ErrFileTemplate = errors.New("struct2csv: the file name template must contain a verb for the file number, e.g. %03d")
+
+// A RotatingWriter writes structs as CSV to a series of files, starting a
+// new file once the current one has reached a maximum number of rows or
+// bytes.  Each file is written by a FileWriter, so files are compressed
+// depending on their extension, and each gets its own header.  Close must
+// be called when done.
+type RotatingWriter struct {
+	template  string
+	opts      []FileOption
+	maxRows   int
+	maxBytes  int64
+	configure func(*Writer)
+	w         *FileWriter // the current file's writer
+	files     []string
+	rows      []int // the number of structs written to each file
+	n         int   // the number of structs the RotatingWriter has been given
+}
+
+// NewRotatingWriter returns a RotatingWriter that names its files using
+// template, a fmt format with a verb for the file number, starting at 1,
+// e.g. "export-%03d.csv.gz".  The options are used for each file.  No file
+// is created until something is written.
+func NewRotatingWriter(template string, opts ...FileOption) (*RotatingWriter, error) {
+	name := fmt.Sprintf(template, 1)
+	if strings.Contains(name, "%!") || name == fmt.Sprintf(template, 2) {
+		return nil, ErrFileTemplate
+	}
+	return &RotatingWriter{template: template, opts: opts}, nil
+}
+
+// SetMaxRows sets the maximum number of structs written to a file, not
+// counting the header.  0, the default, means no limit.
+func (w *RotatingWriter) SetMaxRows(n int) {
+	w.maxRows = n
+}
+
+// SetMaxBytes sets the maximum size of a file, in bytes, including the
+// header.  A new file is started when writing the next struct would exceed
+// it, but each file gets at least one struct, even if it exceeds the
+// maximum.  The size is that of the CSV, after transcoding to the Writer's
+// Charset but before compression.  0, the default, means no limit.
+func (w *RotatingWriter) SetMaxBytes(n int64) {
+	w.maxBytes = n
+}
+
+// Configure sets a func that is called with the Writer of each file, when
+// the file is created, to configure it, e.g. to set its Dialect.
+func (w *RotatingWriter) Configure(fn func(*Writer)) {
+	w.configure = fn
+}
+
+// WriteStruct writes st, a struct, as a CSV record, starting a new file
+// first if the current one is full; the column names are written as the
+// first row of each file, unless that has been turned off with
+// SetWriteHeader(false) on its Writer.  Errors are handled as they are by
+// Writer.WriteStruct, with the Row of a *FieldError being the index of the
+// record in the current file and its Index being the number of structs the
+// RotatingWriter was given before st.
+func (w *RotatingWriter) WriteStruct(st interface{}) error {
+	if w.w == nil {
+		err := w.next(st)
+		if err != nil {
+			return err
+		}
+	}
+	row, err := w.w.encodeRow(st, w.n)
+	w.n++
+	if row == nil {
+		return err
+	}
+	val := reflect.ValueOf(st)
+	if w.full(row, val) {
+		err := w.next(st)
+		if err != nil {
+			return err
+		}
+	}
+	werr := w.w.writeRow(row, val)
+	if werr != nil {
+		return werr
+	}
+	w.rows[len(w.rows)-1]++
+	return err
+}
+
+// full returns whether or not row, encoded from val, a struct, should go in
+// a new file.
+func (w *RotatingWriter) full(row []string, val reflect.Value) bool {
+	n := w.rows[len(w.rows)-1]
+	if n == 0 {
+		return false
+	}
+	if w.maxRows > 0 && n >= w.maxRows {
+		return true
+	}
+	return w.maxBytes > 0 && w.w.Bytes()+w.w.rowSize(row, val) > w.maxBytes
+}
+
+// next closes the current file, if any, and creates the next one, writing
+// the column names of st as its header.
+func (w *RotatingWriter) next(st interface{}) error {
+	if w.w != nil {
+		err := w.w.Close()
+		w.w = nil
+		if err != nil {
+			return err
+		}
+	}
+	name := fmt.Sprintf(w.template, len(w.files)+1)
+	fw, err := NewFileWriter(name, w.opts...)
+	if err != nil {
+		return err
+	}
+	w.w = fw
+	w.files = append(w.files, name)
+	w.rows = append(w.rows, 0)
+	if w.configure != nil {
+		w.configure(fw.Writer)
+	}
+	if fw.writeHdr && !fw.hdrDone {
+		return fw.WriteColNames(st)
+	}
+	return nil
+}
+
+// WriteStructs takes a slice of structs and writes each of them as a CSV
+// record, starting new files as needed.  If a field can't be encoded, what
+// happens depends on the OnError func and the Encoder's ErrorPolicy, as it
+// does for Writer.WriteStructs.
+func (w *RotatingWriter) WriteStructs(st interface{}) error {
+	val, err := structSlice(st)
+	if err != nil {
+		return err
+	}
+	var errs MultiError
+	for i := 0; i < val.Len(); i++ {
+		err = w.WriteStruct(val.Index(i).Interface())
+		if err == nil {
+			continue
+		}
+		if !isEncodingError(err) || w.w.onError != nil || w.w.e.policy == AbortOnError {
+			return err
+		}
+		errs = appendErrors(errs, err)
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// Close closes the current file.
+func (w *RotatingWriter) Close() error {
+	if w.w == nil {
+		return nil
+	}
+	err := w.w.Close()
+	w.w = nil
+	return err
+}
+
+// Files returns the names of the files created, in order.
+func (w *RotatingWriter) Files() []string {
+	return append([]string(nil), w.files...)
+}
+
+// Rows returns the number of structs written to each of the files, not
+// counting the header.
+func (w *RotatingWriter) Rows() []int {
+	return append([]int(nil), w.rows...)
+}
@@ -0,0 +1,152 @@
+package struct2csv
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type Part struct {
+	ID   int
+	Name string
+}
+
+var parts = []Part{{1, "bolt"}, {2, "nut_"}, {3, "gear"}, {4, "cog_"}, {5, "pin_"}}
+
+func TestRotatingWriter(t *testing.T) {
+	tsts := []struct {
+		maxRows  int
+		maxBytes int64
+		dialect  Dialect
+		expected []string
+	}{
+		{0, 0, DefaultDialect, []string{"ID,Name\n1,bolt\n2,nut_\n3,gear\n4,cog_\n5,pin_\n"}},
+		{2, 0, DefaultDialect, []string{"ID,Name\n1,bolt\n2,nut_\n", "ID,Name\n3,gear\n4,cog_\n", "ID,Name\n5,pin_\n"}},
+		{0, 22, DefaultDialect, []string{"ID,Name\n1,bolt\n2,nut_\n", "ID,Name\n3,gear\n4,cog_\n", "ID,Name\n5,pin_\n"}},
+		{0, 10, DefaultDialect, []string{"ID,Name\n1,bolt\n", "ID,Name\n2,nut_\n", "ID,Name\n3,gear\n", "ID,Name\n4,cog_\n", "ID,Name\n5,pin_\n"}},
+		{1, 1000, TSV, []string{"ID\tName\n1\tbolt\n", "ID\tName\n2\tnut_\n", "ID\tName\n3\tgear\n", "ID\tName\n4\tcog_\n", "ID\tName\n5\tpin_\n"}},
+		{3, 22, RFC4180, []string{"ID,Name\r\n1,bolt\r\n", "ID,Name\r\n2,nut_\r\n", "ID,Name\r\n3,gear\r\n", "ID,Name\r\n4,cog_\r\n", "ID,Name\r\n5,pin_\r\n"}},
+	}
+	for i, test := range tsts {
+		dir := t.TempDir()
+		w, err := NewRotatingWriter(filepath.Join(dir, "parts-%02d.csv"))
+		if err != nil {
+			t.Errorf("%d: unexpected error: %s", i, err)
+			continue
+		}
+		w.SetMaxRows(test.maxRows)
+		w.SetMaxBytes(test.maxBytes)
+		dialect := test.dialect
+		w.Configure(func(cw *Writer) { cw.SetDialect(dialect) })
+		err = w.WriteStructs(parts)
+		if err != nil {
+			t.Errorf("%d: unexpected error: %s", i, err)
+		}
+		err = w.Close()
+		if err != nil {
+			t.Errorf("%d: unexpected error: %s", i, err)
+		}
+		files := w.Files()
+		if len(files) != len(test.expected) {
+			t.Errorf("%d: expected %d files, got %d", i, len(test.expected), len(files))
+			continue
+		}
+		var total int
+		for j, name := range files {
+			if filepath.Base(name) != strings.Replace("parts-0N.csv", "N", string(rune('1'+j)), 1) {
+				t.Errorf("%d: expected file %d to be parts-0%d.csv, got %s", i, j, j+1, name)
+			}
+			b, err := os.ReadFile(name)
+			if err != nil {
+				t.Errorf("%d: unexpected error: %s", i, err)
+				continue
+			}
+			if string(b) != test.expected[j] {
+				t.Errorf("%d: file %d: expected %q, got %q", i, j, test.expected[j], b)
+			}
+			if w.Rows()[j] != strings.Count(test.expected[j], "\n")-1 {
+				t.Errorf("%d: file %d: expected %d rows, got %d", i, j, strings.Count(test.expected[j], "\n")-1, w.Rows()[j])
+			}
+			if test.maxBytes > 0 && w.Rows()[j] > 1 && int64(len(b)) > test.maxBytes {
+				t.Errorf("%d: file %d: %d bytes exceeds the maximum of %d", i, j, len(b), test.maxBytes)
+			}
+			total += w.Rows()[j]
+		}
+		if total != len(parts) {
+			t.Errorf("%d: expected %d rows in total, got %d", i, len(parts), total)
+		}
+	}
+}
+
+func TestRotatingWriterCharset(t *testing.T) {
+	dir := t.TempDir()
+	w, err := NewRotatingWriter(filepath.Join(dir, "parts-%d.csv"))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	w.Configure(func(cw *Writer) { cw.SetCharset(UTF16LE) })
+	w.SetMaxBytes(50)
+	err = w.WriteStructs(parts)
+	if err != nil {
+		t.Errorf("unexpected error: %s", err)
+	}
+	err = w.Close()
+	if err != nil {
+		t.Errorf("unexpected error: %s", err)
+	}
+	// the header and each row are 16 and 14 bytes in UTF-16, so each
+	// file gets two rows
+	if !reflect.DeepEqual(w.Rows(), []int{2, 2, 1}) {
+		t.Errorf("expected rows [2 2 1], got %v", w.Rows())
+	}
+	for _, name := range w.Files() {
+		fi, err := os.Stat(name)
+		if err != nil {
+			t.Errorf("unexpected error: %s", err)
+			continue
+		}
+		if fi.Size() > 50 {
+			t.Errorf("%s: %d bytes exceeds the maximum of 50", name, fi.Size())
+		}
+	}
+}
+
+func TestRotatingWriterTemplate(t *testing.T) {
+	_, err := NewRotatingWriter("parts.csv")
+	if err != ErrFileTemplate {
+		t.Errorf("expected %v, got %v", ErrFileTemplate, err)
+	}
+	w, err := NewRotatingWriter(filepath.Join(t.TempDir(), "parts-%d.csv"))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	// nothing written, no files
+	err = w.Close()
+	if err != nil {
+		t.Errorf("unexpected error: %s", err)
+	}
+	if len(w.Files()) != 0 || !reflect.DeepEqual(w.Rows(), []int(nil)) {
+		t.Errorf("expected no files, got %v", w.Files())
+	}
+}
+
+func TestWriterBytes(t *testing.T) {
+	var buff bytes.Buffer
+	w := NewWriter(&buff)
+	w.SetCharset(Latin1)
+	err := w.WriteStruct(Part{1, "écrou"})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	// after transcoding
+	if w.Bytes() != 8 {
+		t.Errorf("expected 8 bytes, got %d", w.Bytes())
+	}
+	w.Flush()
+	if w.Bytes() != int64(buff.Len()) || buff.Len() != 8 {
+		t.Errorf("expected %d bytes, got %d", buff.Len(), w.Bytes())
+	}
+}
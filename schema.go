@@ -0,0 +1,374 @@
+package struct2csv
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// dataType is the logical type of a column's values.
+type dataType int
+
+const (
+	typeString dataType = iota
+	typeInteger
+	typeUnsigned
+	typeNumber
+	typeBoolean
+	typeDateTime
+)
+
+// A ColumnSchema describes a column of the encoded data.
+type ColumnSchema struct {
+	Name  string
+	Label string
+	// Kind is the Kind of the field, ignoring pointers.  For types that
+	// implement driver.Valuer, like sql.NullInt64, it is the Kind of the
+	// value they hold.
+	Kind reflect.Kind
+	// Nullable is whether or not the column can hold NULL values: the
+	// field is a pointer, is nested in a struct pointer, or implements
+	// driver.Valuer.
+	Nullable bool
+	// Format is how the values are formatted, using the syntax of the tag
+	// options, e.g. "format=f,prec=2", "base=16", or "fmt=cents".  Times
+	// are "RFC3339".  Floats always have their format, e.g. "format=E"
+	// for the default; other values have an empty Format if they use the
+	// defaults.
+	Format string
+	// Description comes from the field's csvdoc tag.
+	Description string
+
+	text   dataType // the type of the values in CSV
+	native dataType // the type of the values, ignoring formatting
+	key    string   // the name qualified by its groups, if it isn't unique, as in JSON Lines
+}
+
+// uniqueName returns the name of the column, qualified by its groups if it
+// isn't unique.
+func (cs ColumnSchema) uniqueName() string {
+	if cs.key != "" {
+		return cs.key
+	}
+	return cs.Name
+}
+
+// Schema returns the schema of the columns of v, a struct or a slice of
+// structs.  The schema reflects the Encoder's configuration, e.g. ints
+// encoded using a base other than 10 are strings.
+func (e *Encoder) Schema(v interface{}) ([]ColumnSchema, error) {
+	typ := reflect.TypeOf(v)
+	if typ.Kind() == reflect.Slice {
+		typ = typ.Elem()
+	}
+	if typ.Kind() != reflect.Struct {
+		return nil, StructRequiredError{typ.Kind()}
+	}
+	cols := e.columns(typ, nil, nil)
+	// keys are only for JSONSchema; a JSONLWriter can't write columns
+	// without unique keys
+	keys, _ := columnKeys(cols)
+	schema := make([]ColumnSchema, len(cols))
+	for i, col := range cols {
+		schema[i] = e.columnSchema(col)
+		if keys != nil && keys[i] != col.name {
+			schema[i].key = keys[i]
+		}
+	}
+	return schema, nil
+}
+
+// columnSchema returns the schema of col.
+func (e *Encoder) columnSchema(col column) ColumnSchema {
+	cs := ColumnSchema{
+		Name: col.name, Label: col.label, Description: col.opts.doc,
+		Kind: ptrKind(col.typ), Nullable: col.typ.Kind() == reflect.Ptr || col.optional,
+	}
+	if col.opts.formatter != "" {
+		cs.Format = "fmt=" + col.opts.formatter
+		return cs
+	}
+	typ := col.typ
+	for typ.Kind() == reflect.Ptr {
+		if _, ok := e.typeEncoder(typ); ok {
+			return cs
+		}
+		typ = typ.Elem()
+	}
+	if _, ok := e.typeEncoder(typ); ok {
+		return cs
+	}
+	if isValuer(typ) || isValuer(reflect.PtrTo(typ)) {
+		cs.Nullable = true
+		if !isSQLNull(typ) {
+			return cs
+		}
+		typ = typ.Field(0).Type
+		cs.Kind = typ.Kind()
+	}
+	if typ == timeType {
+		cs.Format = "RFC3339"
+		cs.text, cs.native = typeDateTime, typeDateTime
+		return cs
+	}
+	cs.native = kindType(cs.Kind)
+	cs.text = cs.native
+	var format []string
+	switch cs.native {
+	case typeInteger, typeUnsigned:
+		base := e.intBase
+		if cs.native == typeUnsigned {
+			base = e.uintBase
+		}
+		if col.opts.base != 0 {
+			base = col.opts.base
+		}
+		if base != 10 {
+			format = append(format, "base="+strconv.Itoa(base))
+			cs.text = typeString
+		}
+		if col.opts.prefix && base != 10 {
+			format = append(format, "prefix")
+		}
+		if col.opts.digits > 0 {
+			format = append(format, "digits="+strconv.Itoa(col.opts.digits))
+		}
+		if base == 10 && e.numFmt != nil {
+			cs.text = typeString
+		}
+	case typeNumber:
+		f, prec := e.floatFmt, e.prec
+		if col.opts.floatFmt != 0 {
+			f = col.opts.floatFmt
+		}
+		if col.opts.hasPrec {
+			prec = col.opts.prec
+		}
+		format = append(format, "format="+string(f))
+		if prec >= 0 {
+			format = append(format, "prec="+strconv.Itoa(prec))
+		}
+		if f == 'b' || f == 'x' || f == 'X' || e.numFmt != nil {
+			cs.text = typeString
+		}
+	case typeBoolean:
+		if e.boolFmt != nil {
+			cs.text = typeString
+		}
+	case typeString:
+		if cs.Kind == reflect.Slice || cs.Kind == reflect.Array {
+			if enc, ok := e.bytesEncoding(typ, col.opts); ok {
+				format = append(format, [...]string{BytesHex: "hex", BytesBase64: "base64", BytesRaw: "raw"}[enc])
+			}
+		}
+	}
+	cs.Format = strings.Join(format, ",")
+	return cs
+}
+
+// isSQLNull returns whether or not typ is one of the database/sql Null
+// types, e.g. sql.NullInt64, which hold their value in their first field.
+// Other Valuers are strings, as what their Value method returns isn't
+// known.
+func isSQLNull(typ reflect.Type) bool {
+	return typ.Kind() == reflect.Struct && typ.PkgPath() == "database/sql" &&
+		strings.HasPrefix(typ.Name(), "Null") && typ.NumField() == 2
+}
+
+// kindType returns the dataType of values of Kind k.
+func kindType(k reflect.Kind) dataType {
+	switch k {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return typeInteger
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return typeUnsigned
+	case reflect.Float32, reflect.Float64:
+		return typeNumber
+	case reflect.Bool:
+		return typeBoolean
+	}
+	return typeString
+}
+
+// keyValue is a member of an orderedObject.
+type keyValue struct {
+	key   string
+	value interface{}
+}
+
+// orderedObject is a JSON object whose members are kept in order.
+type orderedObject []keyValue
+
+func (o orderedObject) MarshalJSON() ([]byte, error) {
+	var b bytes.Buffer
+	b.WriteByte('{')
+	for i, kv := range o {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		err := writeJSON(&b, kv.key)
+		if err != nil {
+			return nil, err
+		}
+		b.WriteByte(':')
+		err = writeJSON(&b, kv.value)
+		if err != nil {
+			return nil, err
+		}
+	}
+	b.WriteByte('}')
+	return b.Bytes(), nil
+}
+
+var csvwTypes = [...]string{
+	typeString: "string", typeInteger: "integer", typeUnsigned: "nonNegativeInteger",
+	typeNumber: "double", typeBoolean: "boolean", typeDateTime: "dateTime",
+}
+
+// CSVW returns W3C CSV on the Web metadata, as JSON, describing the CSV at
+// url, with the columns of the schema.  If the CSV's NULL values aren't
+// empty, null is the NULL token.  As CSVW treats empty cells as NULL by
+// default, string columns are only required if null isn't empty; otherwise,
+// empty strings can't be told apart from NULL values.
+func CSVW(url string, schema []ColumnSchema, null string) ([]byte, error) {
+	cols := make([]orderedObject, len(schema))
+	for i, cs := range schema {
+		col := orderedObject{{"titles", cs.Name}}
+		if cs.Label != cs.Name && cs.Label != "" {
+			col[0].value = []string{cs.Name, cs.Label}
+		}
+		if cs.Description != "" {
+			col = append(col, keyValue{"dc:description", cs.Description})
+		}
+		col = append(col, keyValue{"datatype", csvwTypes[cs.text]})
+		required := !cs.Nullable
+		if cs.text == typeString && null == "" {
+			required = false
+		}
+		col = append(col, keyValue{"required", required})
+		if null != "" && (cs.Nullable || cs.text == typeString) {
+			// otherwise, empty strings would be NULL
+			col = append(col, keyValue{"null", null})
+		}
+		cols[i] = col
+	}
+	return json.MarshalIndent(orderedObject{
+		{"@context", "http://www.w3.org/ns/csvw"},
+		{"url", url},
+		{"tableSchema", orderedObject{{"columns", cols}}},
+	}, "", "  ")
+}
+
+var jsonSchemaTypes = [...]string{
+	typeString: "string", typeInteger: "integer", typeUnsigned: "integer",
+	typeNumber: "number", typeBoolean: "boolean", typeDateTime: "string",
+}
+
+// JSONSchema returns a JSON Schema describing the objects written by a
+// JSONLWriter with the columns of the schema.
+func JSONSchema(schema []ColumnSchema) ([]byte, error) {
+	props := make(orderedObject, len(schema))
+	required := []string{}
+	for i, cs := range schema {
+		var typ interface{} = jsonSchemaTypes[cs.native]
+		if cs.Nullable {
+			typ = []string{jsonSchemaTypes[cs.native], "null"}
+		}
+		prop := orderedObject{{"type", typ}}
+		switch cs.native {
+		case typeUnsigned:
+			prop = append(prop, keyValue{"minimum", 0})
+		case typeNumber:
+			// NaN and infinities are strings
+			if cs.Nullable {
+				typ = []string{"number", "string", "null"}
+			} else {
+				typ = []string{"number", "string"}
+			}
+			prop[0].value = typ
+		case typeDateTime:
+			prop = append(prop, keyValue{"format", "date-time"})
+		}
+		if cs.Label != cs.Name && cs.Label != "" {
+			prop = append(prop, keyValue{"title", cs.Label})
+		}
+		if cs.Description != "" {
+			prop = append(prop, keyValue{"description", cs.Description})
+		}
+		key := cs.uniqueName()
+		props[i] = keyValue{key, prop}
+		required = append(required, key)
+	}
+	return json.MarshalIndent(orderedObject{
+		{"$schema", "https://json-schema.org/draft/2020-12/schema"},
+		{"type", "object"},
+		{"properties", props},
+		{"required", required},
+	}, "", "  ")
+}
+
+// SQLDialect is the dialect of the SQL generated by CreateTable.
+type SQLDialect int
+
+const (
+	// PostgreSQL is the SQL dialect of PostgreSQL.
+	PostgreSQL SQLDialect = iota
+	// SQLite is the SQL dialect of SQLite.
+	SQLite
+)
+
+var sqlTypes = [...][6]string{
+	PostgreSQL: {
+		typeString: "TEXT", typeInteger: "BIGINT", typeUnsigned: "NUMERIC(20)",
+		typeNumber: "DOUBLE PRECISION", typeBoolean: "BOOLEAN", typeDateTime: "TIMESTAMPTZ",
+	},
+	SQLite: {
+		typeString: "TEXT", typeInteger: "INTEGER", typeUnsigned: "NUMERIC",
+		typeNumber: "REAL", typeBoolean: "BOOLEAN", typeDateTime: "TEXT",
+	},
+}
+
+// CreateTable returns a CREATE TABLE statement for a table, called table,
+// that the CSV with the columns of the schema can be loaded into.  Columns
+// whose names aren't unique are named as they are by JSONSchema, e.g.
+// "Home.Street".  Columns that aren't nullable are NOT NULL.  Descriptions are added as comments:
+// for PostgreSQL, using COMMENT ON COLUMN statements.
+func CreateTable(table string, schema []ColumnSchema, d SQLDialect) string {
+	if d != SQLite {
+		d = PostgreSQL
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "CREATE TABLE %s (\n", quoteIdent(table))
+	for i, cs := range schema {
+		fmt.Fprintf(&b, "  %s %s", quoteIdent(cs.uniqueName()), sqlTypes[d][cs.text])
+		if !cs.Nullable {
+			b.WriteString(" NOT NULL")
+		}
+		if i < len(schema)-1 {
+			b.WriteString(",")
+		}
+		if d == SQLite && cs.Description != "" {
+			b.WriteString(" -- " + strings.Join(strings.Fields(cs.Description), " "))
+		}
+		b.WriteString("\n")
+	}
+	b.WriteString(");\n")
+	if d != PostgreSQL {
+		return b.String()
+	}
+	for _, cs := range schema {
+		if cs.Description == "" {
+			continue
+		}
+		fmt.Fprintf(&b, "COMMENT ON COLUMN %s.%s IS '%s';\n", quoteIdent(table), quoteIdent(cs.uniqueName()), strings.ReplaceAll(cs.Description, "'", "''"))
+	}
+	return b.String()
+}
+
+// quoteIdent returns the SQL identifier s, quoted.
+func quoteIdent(s string) string {
+	return `"` + strings.ReplaceAll(s, `"`, `""`) + `"`
+}
@@ -0,0 +1,226 @@
+package struct2csv
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+type Member struct {
+	ID      int64           `csv:"id" csvdoc:"The member's ID"`
+	Name    string          `csv:"name,label=Full Name"`
+	Balance float64         `csv:"balance,format=f,prec=2"`
+	Flags   uint8           `csv:"flags,base=2"`
+	Active  bool            `csv:"active"`
+	Email   *string         `csv:"email"`
+	Score   sql.NullFloat64 `csv:"score"`
+	Joined  time.Time       `csv:"joined"`
+	Avatar  []byte          `csv:"avatar,base64"`
+	Dues    nullCents       `csv:"dues"`
+}
+
+// nullCents is a Valuer shaped like the sql.Null types, whose Value isn't
+// the type of its first field.
+type nullCents struct {
+	Cents int64
+	Valid bool
+}
+
+func (c nullCents) Value() (driver.Value, error) {
+	if !c.Valid {
+		return nil, nil
+	}
+	return fmt.Sprintf("%d.%02d", c.Cents/100, c.Cents%100), nil
+}
+
+func TestSchema(t *testing.T) {
+	schema, err := New().Schema([]Member{})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	expected := []ColumnSchema{
+		{Name: "id", Label: "id", Kind: reflect.Int64, Description: "The member's ID", text: typeInteger, native: typeInteger},
+		{Name: "name", Label: "Full Name", Kind: reflect.String},
+		{Name: "balance", Label: "balance", Kind: reflect.Float64, Format: "format=f,prec=2", text: typeNumber, native: typeNumber},
+		{Name: "flags", Label: "flags", Kind: reflect.Uint8, Format: "base=2", text: typeString, native: typeUnsigned},
+		{Name: "active", Label: "active", Kind: reflect.Bool, text: typeBoolean, native: typeBoolean},
+		{Name: "email", Label: "email", Kind: reflect.String, Nullable: true},
+		{Name: "score", Label: "score", Kind: reflect.Float64, Nullable: true, Format: "format=E", text: typeNumber, native: typeNumber},
+		{Name: "joined", Label: "joined", Kind: reflect.Struct, Format: "RFC3339", text: typeDateTime, native: typeDateTime},
+		{Name: "avatar", Label: "avatar", Kind: reflect.Slice, Format: "base64"},
+		{Name: "dues", Label: "dues", Kind: reflect.Struct, Nullable: true},
+	}
+	if !reflect.DeepEqual(schema, expected) {
+		t.Errorf("expected %+v, got %+v", expected, schema)
+	}
+	_, err = New().Schema(1)
+	if _, ok := err.(StructRequiredError); !ok {
+		t.Errorf("expected a StructRequiredError, got %v", err)
+	}
+}
+
+func TestSchemaExport(t *testing.T) {
+	type Row struct {
+		ID    int      `csv:"id" csvdoc:"Row's ID"`
+		Score *float64 `csv:"score,label=Score"`
+		At    time.Time
+	}
+	schema, err := New().Schema(Row{})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	b, err := CSVW("rows.csv", schema, `\N`)
+	if err != nil {
+		t.Errorf("unexpected error: %s", err)
+	}
+	expected := `{
+  "@context": "http://www.w3.org/ns/csvw",
+  "url": "rows.csv",
+  "tableSchema": {
+    "columns": [
+      {
+        "titles": "id",
+        "dc:description": "Row's ID",
+        "datatype": "integer",
+        "required": true
+      },
+      {
+        "titles": [
+          "score",
+          "Score"
+        ],
+        "datatype": "double",
+        "required": false,
+        "null": "\\N"
+      },
+      {
+        "titles": "At",
+        "datatype": "dateTime",
+        "required": true
+      }
+    ]
+  }
+}`
+	if string(b) != expected {
+		t.Errorf("expected %s, got %s", expected, b)
+	}
+
+	b, err = JSONSchema(schema)
+	if err != nil {
+		t.Errorf("unexpected error: %s", err)
+	}
+	expected = `{
+  "$schema": "https://json-schema.org/draft/2020-12/schema",
+  "type": "object",
+  "properties": {
+    "id": {
+      "type": "integer",
+      "description": "Row's ID"
+    },
+    "score": {
+      "type": [
+        "number",
+        "string",
+        "null"
+      ],
+      "title": "Score"
+    },
+    "At": {
+      "type": "string",
+      "format": "date-time"
+    }
+  },
+  "required": [
+    "id",
+    "score",
+    "At"
+  ]
+}`
+	if string(b) != expected {
+		t.Errorf("expected %s, got %s", expected, b)
+	}
+
+	tsts := []struct {
+		d        SQLDialect
+		expected string
+	}{
+		{PostgreSQL, "CREATE TABLE \"rows\" (\n" +
+			"  \"id\" BIGINT NOT NULL,\n" +
+			"  \"score\" DOUBLE PRECISION,\n" +
+			"  \"At\" TIMESTAMPTZ NOT NULL\n" +
+			");\n" +
+			"COMMENT ON COLUMN \"rows\".\"id\" IS 'Row''s ID';\n"},
+		{SQLite, "CREATE TABLE \"rows\" (\n" +
+			"  \"id\" INTEGER NOT NULL, -- Row's ID\n" +
+			"  \"score\" REAL,\n" +
+			"  \"At\" TEXT NOT NULL\n" +
+			");\n"},
+	}
+	for _, test := range tsts {
+		ddl := CreateTable("rows", schema, test.d)
+		if ddl != test.expected {
+			t.Errorf("%d: expected %q, got %q", test.d, test.expected, ddl)
+		}
+	}
+}
+
+// Empty strings are NULL in CSVW unless the NULL token is set.
+func TestCSVWStrings(t *testing.T) {
+	type Row struct {
+		Name string
+	}
+	schema, err := New().Schema(Row{})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	tsts := []struct {
+		null     string
+		expected string
+	}{
+		{"", `"required": false
+      }`},
+		{`\N`, `"required": true,
+        "null": "\\N"
+      }`},
+	}
+	for _, test := range tsts {
+		b, err := CSVW("rows.csv", schema, test.null)
+		if err != nil {
+			t.Errorf("%q: unexpected error: %s", test.null, err)
+			continue
+		}
+		if !strings.Contains(string(b), test.expected) {
+			t.Errorf("%q: expected %s in %s", test.null, test.expected, b)
+		}
+	}
+}
+
+func TestSchemaKeys(t *testing.T) {
+	type Person struct {
+		Home Address
+		Work Address
+	}
+	schema, err := New().Schema(Person{})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	b, err := JSONSchema(schema)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	for _, key := range []string{`"Home.City": {`, `"Work.City": {`} {
+		if !strings.Contains(string(b), key) {
+			t.Errorf("expected %s in %s", key, b)
+		}
+	}
+	ddl := CreateTable("people", schema, PostgreSQL)
+	for _, col := range []string{`"Home.City" TEXT NOT NULL`, `"Work.City" TEXT NOT NULL`} {
+		if !strings.Contains(ddl, col) {
+			t.Errorf("expected %s in %s", col, ddl)
+		}
+	}
+}
@@ -0,0 +1,40 @@
+package struct2csv
+
+import (
+	"database/sql/driver"
+	"reflect"
+	"time"
+)
+
+var (
+	valuerType = reflect.TypeOf((*driver.Valuer)(nil)).Elem()
+	timeType   = reflect.TypeOf(time.Time{})
+)
+
+// isValuer returns whether or not typ implements driver.Valuer.
+func isValuer(typ reflect.Type) bool {
+	return typ.Implements(valuerType)
+}
+
+// encodeValuer encodes val, which must implement driver.Valuer, e.g.
+// sql.NullString, using the value returned by its Value method.  A nil
+// driver.Value, e.g. from an invalid sql.NullString, is encoded as the
+// Encoder's null token.
+func (e *Encoder) encodeValuer(val reflect.Value, child bool, opts fieldOptions) (string, error) {
+	if val.Kind() == reflect.Ptr && val.IsNil() {
+		return e.null, nil
+	}
+	v, err := val.Interface().(driver.Valuer).Value()
+	if err != nil {
+		return "", err
+	}
+	switch v := v.(type) {
+	case nil:
+		return e.null, nil
+	case time.Time:
+		return v.Format(time.RFC3339Nano), nil
+	case []byte:
+		return string(v), nil
+	}
+	return e.stringify(reflect.ValueOf(v), child, opts)
+}
@@ -0,0 +1,69 @@
+package struct2csv
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type Customer struct {
+	ID      int64
+	Name    sql.NullString
+	Age     sql.NullInt64
+	Balance sql.NullFloat64
+	Active  sql.NullBool
+	Since   sql.NullTime
+	Ref     *sql.NullString
+	Nick    *string
+}
+
+func TestSQLNullTypes(t *testing.T) {
+	since := time.Date(2015, 11, 22, 10, 30, 0, 0, time.UTC)
+	customers := []Customer{
+		Customer{
+			ID:      1,
+			Name:    sql.NullString{String: "Arthur Dent", Valid: true},
+			Age:     sql.NullInt64{Int64: 42, Valid: true},
+			Balance: sql.NullFloat64{Float64: 1.5, Valid: true},
+			Active:  sql.NullBool{Bool: true, Valid: true},
+			Since:   sql.NullTime{Time: since, Valid: true},
+			Ref:     &sql.NullString{String: "HHGTTG", Valid: true},
+		},
+		Customer{ID: 2},
+	}
+	expected := [][]string{
+		[]string{"ID", "Name", "Age", "Balance", "Active", "Since", "Ref", "Nick"},
+		[]string{"1", "Arthur Dent", "42", "1.5E+00", "true", "2015-11-22T10:30:00Z", "HHGTTG", `\N`},
+		[]string{"2", `\N`, `\N`, `\N`, `\N`, `\N`, `\N`, `\N`},
+	}
+	enc := New()
+	enc.SetNullToken(`\N`)
+	rows, err := enc.Marshal(customers)
+	if err != nil {
+		t.Errorf("unexpected error: %s", err)
+		return
+	}
+	if !reflect.DeepEqual(rows, expected) {
+		t.Errorf("expected %q, got %q", expected, rows)
+	}
+}
+
+type badValuer struct{}
+
+var errBadValue = errors.New("bad value")
+
+func (badValuer) Value() (driver.Value, error) { return nil, errBadValue }
+
+func TestValuerError(t *testing.T) {
+	enc := New()
+	_, err := enc.GetRow(struct {
+		ID  int
+		Bad badValuer
+	}{})
+	if !errors.Is(err, errBadValue) {
+		t.Errorf("expected %q, got %v", errBadValue, err)
+	}
+}
@@ -20,12 +20,16 @@
 package struct2csv
 
 import (
+	"encoding/base64"
+	"encoding/hex"
 	"errors"
 	"fmt"
+	"math"
 	"reflect"
 	"sort"
 	"strconv"
 	"strings"
+	"time"
 )
 
 // A StructRequiredError is returned when a non-struct type is received.
@@ -56,6 +60,10 @@ var (
 	ErrNilSlice = errors.New("struct2csv: the slice of structs was nil")
 	// ErrEmptySlice occurs when the slice of structs to encode is empty.
 	ErrEmptySlice = errors.New("struct2csv: the slice of structs was empty")
+
+	// errUnsupportedKind is used to signal that a value is of an unsupported
+	// Kind and should be skipped; it is never returned by exported funcs.
+	errUnsupportedKind = errors.New("struct2csv: unsupported kind")
 )
 
 // Below is implemented from
@@ -74,33 +82,98 @@ func (sv stringValues) Swap(i, j int)      { sv[i], sv[j] = sv[j], sv[i] }
 func (sv stringValues) Less(i, j int) bool { return sv.get(i) < sv.get(j) }
 func (sv stringValues) get(i int) string   { return sv[i].String() }
 
+// BytesEncoding is how []byte and [N]byte values are encoded.
+type BytesEncoding int
+
+const (
+	// BytesList encodes bytes as a list of numbers, like any other slice.
+	// This is the default.
+	BytesList BytesEncoding = iota
+	// BytesHex encodes bytes as a hex string.
+	BytesHex
+	// BytesBase64 encodes bytes using standard base64 encoding.
+	BytesBase64
+	// BytesRaw uses the bytes, as is, as the string value.
+	BytesRaw
+)
+
 // Encoder handles encoding of a CSV from a struct.
 type Encoder struct {
 	// Whether or not tags should be use for header (column) names; by default this is csv,
-	useTags  bool
-	base     int
-	tag      string // The tag to use when tags are being used for headers; defaults to csv.
-	sepBeg   string
-	sepEnd   string
-	colNames []string
+	useTags     bool
+	intBase     int      // The base used for signed integers; defaults to 10.
+	uintBase    int      // The base used for unsigned integers; defaults to 10.
+	tag         string   // The tag to use when tags are being used for headers; defaults to csv.
+	fallbacks   []string // Tags to check, in order, when a field doesn't have the tag.
+	nameMapper  func(string) string
+	sepBeg      string
+	sepEnd      string
+	floatFmt    byte // The format used by strconv.FormatFloat; defaults to 'E'.
+	prec        int  // The precision used by strconv.FormatFloat; defaults to -1.
+	nan         string
+	posInf      string
+	negInf      string
+	numFmt      NumberFormatter
+	boolFmt     BoolFormatter
+	bytesEnc    BytesEncoding
+	types       map[reflect.Type]EncodeFunc
+	formatters  map[string]EncodeFunc
+	policy      ErrorPolicy
+	placeholder string // used for fields that can't be encoded
+	null        string // the value used for nils; defaults to an empty string.
+	hdrMode     HeaderMode
+	colNames    []string
+	colLabels   []string
 }
 
 // New returns an initialized Encoder.
 func New() *Encoder {
 	return &Encoder{
-		useTags: true, base: 10, tag: "csv",
+		useTags: true, intBase: 10, uintBase: 10, tag: "csv",
 		sepBeg: "(", sepEnd: ")",
+		floatFmt: 'E', prec: -1,
+		nan: "NaN", posInf: "+Inf", negInf: "-Inf",
 	}
 }
 
 // SetTag sets the tag that the Encoder should use for header (column)
 // names.  By default, this is set to 'csv'.  If the received value is an
-// empty string, nothing will be done
+// empty string, nothing will be done.  Any fallback tags set by SetTags are
+// removed.
 func (e *Encoder) SetTag(s string) {
 	if s == "" {
 		return
 	}
 	e.tag = s
+	e.fallbacks = nil
+}
+
+// SetTags sets the tags that the Encoder should use for header (column)
+// names, in order of precedence; e.g. SetTags("csv", "json", "db").  The
+// first tag that has a name is used; options in tags other than the first,
+// like json's omitempty, are ignored.  If none of the tags have a name, the
+// field's name is used.  Empty strings are ignored; if no tags are received,
+// nothing will be done.
+func (e *Encoder) SetTags(tags ...string) {
+	var t []string
+	for _, tag := range tags {
+		if tag != "" {
+			t = append(t, tag)
+		}
+	}
+	if len(t) == 0 {
+		return
+	}
+	e.tag = t[0]
+	e.fallbacks = t[1:]
+}
+
+// SetNameMapper sets the func used to transform field names into column
+// names.  It is used for fields whose name doesn't come from a tag; either
+// because they don't have one or because tags aren't being used.  A nil func
+// results in the field name being used as is, which is the default.
+func (e *Encoder) SetNameMapper(fn func(string) string) {
+	e.nameMapper = fn
 }
 
 // SetUseTags sets whether or not tags should be used for header (column)
@@ -117,15 +190,117 @@ func (e *Encoder) SetSeparators(beg, end string) {
 	e.sepEnd = end
 }
 
-// SetBase sets the base for strings.FormatUint. By default, this is 10. Set
-// the base if another base should be used for formatting uint values.
+// SetBase sets the base for both signed and unsigned integers. By default,
+// this is 10.  Set the base if another base should be used for formatting
+// integer values.  To use different bases for signed and unsigned integers,
+// use SetIntBase and SetUintBase.
 //
-// Base 2 is the minimum value; anything less will be set to two.
+// Base 2 is the minimum value; anything less will be set to two.  Base 36
+// is the maximum value; anything more will be set to 36.
 func (e *Encoder) SetBase(i int) {
+	e.SetIntBase(i)
+	e.SetUintBase(i)
+}
+
+// SetIntBase sets the base for strconv.FormatInt, which is used for signed
+// integers.  By default, this is 10.  The base is limited to the range
+// 2-36.
+func (e *Encoder) SetIntBase(i int) {
+	e.intBase = clampBase(i)
+}
+
+// SetUintBase sets the base for strconv.FormatUint, which is used for
+// unsigned integers.  By default, this is 10.  The base is limited to the
+// range 2-36.
+func (e *Encoder) SetUintBase(i int) {
+	e.uintBase = clampBase(i)
+}
+
+// clampBase returns the closest base supported by strconv to i.
+func clampBase(i int) int {
 	if i < 2 {
-		i = 2
+		return 2
 	}
-	e.base = i
+	if i > 36 {
+		return 36
+	}
+	return i
+}
+
+// SetFloatFormat sets the format and precision used for float values; these
+// are passed to strconv.FormatFloat.  By default, 'E' and -1 are used, which
+// results in 1.5 being encoded as 1.5E+00.  To encode 1.5 as 1.50, use 'f'
+// and 2.  If the format is not one supported by strconv.FormatFloat, nothing
+// will be done.
+//
+// The float format can also be set on individual fields with the format and
+// prec tag options, e.g. `csv:"price,format=f,prec=2"`.
+func (e *Encoder) SetFloatFormat(fmt byte, prec int) {
+	if !isFloatFormat(fmt) {
+		return
+	}
+	e.floatFmt = fmt
+	e.prec = prec
+}
+
+// SetFloatSpecials sets the strings used for float values that are NaN,
+// +Inf, and -Inf.  By default, these are "NaN", "+Inf", and "-Inf".
+func (e *Encoder) SetFloatSpecials(nan, posInf, negInf string) {
+	e.nan = nan
+	e.posInf = posInf
+	e.negInf = negInf
+}
+
+// SetNumberFormatter sets the NumberFormatter used for base 10 integers and
+// floats, e.g. NumberFormat{Decimal: ',', Group: '.'}.  A nil
+// NumberFormatter results in numbers being formatted by strconv, which is
+// the default.
+func (e *Encoder) SetNumberFormatter(f NumberFormatter) {
+	e.numFmt = f
+}
+
+// SetBoolFormatter sets the BoolFormatter used for bool values, e.g.
+// BoolFormat{True: "1", False: "0"}.  A nil BoolFormatter results in bools
+// being encoded as true and false, which is the default.
+func (e *Encoder) SetBoolFormatter(f BoolFormatter) {
+	e.boolFmt = f
+}
+
+// SetBytesEncoding sets how []byte and [N]byte values are encoded.  By
+// default, bytes are encoded as a list of numbers, BytesList.  The encoding
+// can also be set on individual fields with the hex, base64, raw, and list
+// tag options, e.g. `csv:"hash,hex"`.
+func (e *Encoder) SetBytesEncoding(enc BytesEncoding) {
+	e.bytesEnc = enc
+}
+
+// SetNullToken sets the value used for nil pointers and for NULL values,
+// e.g. an invalid sql.NullString.  By default, this is an empty string.
+func (e *Encoder) SetNullToken(s string) {
+	e.null = s
+}
+
+// SetErrorPolicy sets what happens when a struct can't be encoded.  By
+// default, this is AbortOnError.
+func (e *Encoder) SetErrorPolicy(p ErrorPolicy) {
+	e.policy = p
+}
+
+// SetPlaceholder sets the value used for fields that can't be encoded when
+// the ErrorPolicy is PlaceholderOnError.  By default, this is an empty
+// string.
+func (e *Encoder) SetPlaceholder(s string) {
+	e.placeholder = s
+}
+
+// SetHeaderMode sets whether the header uses the column names, HeaderNames,
+// or their display labels, HeaderLabels.  By default, this is HeaderNames.
+//
+// A field's label is the value of its csvlabel tag or its label tag option,
+// e.g. `csv:"cust_id" csvlabel:"Customer ID"` or `csv:"cust_id,label=Cust"`.
+// Fields without a label use their column name.
+func (e *Encoder) SetHeaderMode(m HeaderMode) {
+	e.hdrMode = m
 }
 
 // ColNames returns the encoder's saved column names as a copy.  The
@@ -136,6 +311,14 @@ func (e *Encoder) ColNames() []string {
 	return ret
 }
 
+// ColLabels returns the encoder's saved column labels as a copy.  Like
+// ColNames, these are populated by GetColNames and Marshal.
+func (e *Encoder) ColLabels() []string {
+	ret := make([]string, len(e.colLabels))
+	_ = copy(ret, e.colLabels)
+	return ret
+}
+
 // GetColNames get's the column names from the received struct.  If the
 // interface is not a struct, an error will occur.
 //
@@ -148,57 +331,47 @@ func (e *Encoder) GetColNames(v interface{}) ([]string, error) {
 	if reflect.TypeOf(v).Kind() != reflect.Struct {
 		return nil, StructRequiredError{reflect.TypeOf(v).Kind()}
 	}
-	// the returned bool is ignored because it's only used for recursive calls.
-	names := e.getColNames(v)
-	// keep a copy
-	e.colNames = make([]string, len(names))
-	_ = copy(e.colNames, names)
-	return names, nil
+	return e.getColNames(v), nil
 }
 
-// The private func where the work is done.  This also copies the headers
-// to the Encoder.colNames field.
+// The private func where the work is done.  This also copies the column
+// names and labels to the Encoder's colNames and colLabels fields.  The
+// returned header depends on the HeaderMode.
 func (e *Encoder) getColNames(v interface{}) []string {
-	typ := reflect.TypeOf(v)
-	val := reflect.ValueOf(v)
-	var cols []string
-	for i := 0; i < typ.NumField(); i++ {
-		// skip unexported
-		tF := typ.Field(i)
-		if len(tF.PkgPath) > 0 {
-			continue
-		}
-		name := e.getFieldName(tF)
-		if name == "" {
-			continue
-		}
-		vF := val.Field(i)
-		switch vF.Kind() {
-		case reflect.Struct:
-			tmp := e.getColNames(vF.Interface())
-			cols = append(cols, tmp...)
-			continue
-		default:
-			ok := supportedBaseKind(vF)
-			if !ok {
-				continue
-			}
-		}
-		cols = append(cols, name)
+	cols := e.columns(reflect.TypeOf(v), nil, nil)
+	e.colNames = make([]string, len(cols))
+	e.colLabels = make([]string, len(cols))
+	for i, col := range cols {
+		e.colNames[i] = col.name
+		e.colLabels[i] = col.label
 	}
-	return cols
+	if e.hdrMode == HeaderLabels {
+		return e.ColLabels()
+	}
+	return e.ColNames()
 }
 
 // GetRow get's the data from the passed struct. This only operates on
 // single structs.  If you wish to transmogrify everything at once, use
 // Encoder.Marshal([]T).
+//
+// If a field can't be encoded, a *FieldError is returned; as GetRow doesn't
+// know the struct's position, its Row and Index are -1.  When the
+// ErrorPolicy is PlaceholderOnError, the row is returned along with a
+// MultiError of the fields that were replaced by the placeholder.
 func (e *Encoder) GetRow(v interface{}) ([]string, error) {
 	if reflect.TypeOf(v).Kind() != reflect.Struct {
 		return nil, StructRequiredError{reflect.TypeOf(v).Kind()}
 	}
 	// 2nd parm is only used for recursive calls.
-	cols, _ := e.marshalStruct(v, false)
-	return cols, nil
+	cols, err := e.marshalStruct(v, false)
+	if err != nil {
+		err = rowError(err, -1, -1)
+		if e.policy != PlaceholderOnError {
+			return nil, err
+		}
+	}
+	return cols, err
 }
 
 // Marshal takes a slice of structs and returns a [][]byte representing CSV
@@ -207,119 +380,240 @@ func (e *Encoder) GetRow(v interface{}) ([]string, error) {
 // that are maps are stored in a single column as a comma separted list of
 // key:value pairs.
 //
-// If the passed data isn't a slice of structs an error will be returned.
+// If the passed data isn't a slice of structs an error will be returned.  If
+// a field can't be encoded, a *FieldError is returned; its Row is the index
+// of the row in the returned data, i.e. the header is row 0, and its Index
+// is the index of the struct in v.  What happens to the rest of the data
+// depends on the ErrorPolicy; for policies other than AbortOnError, the
+// encoded data is returned along with a MultiError of all the errors that
+// occurred.
 func (e *Encoder) Marshal(v interface{}) ([][]string, error) {
-	// must be a slice
-	if reflect.TypeOf(v).Kind() != reflect.Slice {
-		return nil, StructSliceError{kind: reflect.TypeOf(v).Kind()}
-	}
-	val := reflect.ValueOf(v)
-	// must be a slice of struct
-	if val.IsNil() {
-		return nil, ErrNilSlice
-	}
-	if val.Len() == 0 {
-		return nil, ErrEmptySlice
+	val, err := structSlice(v)
+	if err != nil {
+		return nil, err
 	}
 	var rows [][]string
 	// get the first value in the slice to get the struct's field names
-	s := val.Index(0)
-	switch s.Kind() {
-	case reflect.Struct:
-		cols := e.getColNames(s.Interface())
-		// keep a copy
-		e.colNames = make([]string, len(cols))
-		_ = copy(e.colNames, cols)
-		// add as a row
-		rows = append(rows, cols)
-	default:
-		return nil, StructSliceError{kind: reflect.Slice, sliceKind: s.Kind()}
-	}
+	cols := e.getColNames(val.Index(0).Interface())
+	// add as a row
+	rows = append(rows, cols)
+	var errs MultiError
 	// go through each element in the slice and marshal the element'd data.
 	for i := 0; i < val.Len(); i++ {
 		s := val.Index(i)
-		row, ok := e.marshalStruct(s.Interface(), false)
-		if !ok {
-			continue
+		row, err := e.marshalStruct(s.Interface(), false)
+		if err != nil {
+			err = rowError(err, i, len(rows))
+			switch e.policy {
+			case SkipOnError:
+				errs = appendErrors(errs, err)
+				continue
+			case PlaceholderOnError:
+				errs = appendErrors(errs, err)
+			default:
+				return nil, err
+			}
 		}
 		rows = append(rows, row)
-
+	}
+	if len(errs) > 0 {
+		return rows, errs
 	}
 	return rows, nil
 }
 
-// marshal returns the marshaled value. If the received value is not of a
-// supported Kind, a nil is returned along with false. For supported kinds, a
-// slice of values is returned along with true.
-func (e *Encoder) marshal(val reflect.Value, child bool) (cols []string, ok bool) {
-	var s string
+// structSlice returns the value of v after checking that it is a slice of
+// structs that isn't empty.
+func structSlice(v interface{}) (reflect.Value, error) {
+	// must be a slice
+	if reflect.TypeOf(v).Kind() != reflect.Slice {
+		return reflect.Value{}, StructSliceError{kind: reflect.TypeOf(v).Kind()}
+	}
+	val := reflect.ValueOf(v)
+	// must be a slice of struct
+	if val.IsNil() {
+		return reflect.Value{}, ErrNilSlice
+	}
+	if val.Len() == 0 {
+		return reflect.Value{}, ErrEmptySlice
+	}
+	if k := val.Index(0).Kind(); k != reflect.Struct {
+		return reflect.Value{}, StructSliceError{kind: reflect.Slice, sliceKind: k}
+	}
+	return val, nil
+}
+
+// marshal returns the marshaled value as a slice of values.  If the received
+// value is not of a supported Kind, errUnsupportedKind is returned.
+func (e *Encoder) marshal(val reflect.Value, child bool, opts fieldOptions) ([]string, error) {
+	s, ok, err := e.encodeLeaf(val, child, opts)
+	if err != nil {
+		return nil, err
+	}
+	if ok {
+		return []string{s}, nil
+	}
 	switch val.Kind() {
 	case reflect.Ptr:
 		// for maps and slices, check that they are of supported types
-		ok = supportedBaseKind(val)
-		if !ok {
-			return nil, false
+		if !supportedBaseKind(val) {
+			return nil, errUnsupportedKind
 		}
 		vv := reflect.Indirect(val)
 		switch vv.Kind() {
 		case reflect.Invalid:
-			// do nothing
+			// a nil struct pointer has a NULL for each of its columns
+			if st, ok := e.ptrStruct(val.Type()); ok {
+				cols := make([]string, len(e.columns(st, nil, nil)))
+				for i := range cols {
+					cols[i] = e.null
+				}
+				return cols, nil
+			}
+			s = e.null
 		default:
-			return e.marshal(vv, child)
+			return e.marshal(vv, child, opts)
 		}
 	case reflect.Struct:
 		return e.marshalStruct(val.Interface(), true)
 	case reflect.Map:
-		s, ok = e.marshalMap(val, child)
-		if !ok {
-			return nil, false
-		}
+		s, err = e.marshalMap(val, child, opts)
 	case reflect.Array, reflect.Slice:
-		s, ok = e.marshalSlice(val, child)
-		if !ok {
-			return nil, false
+		if enc, ok := e.bytesEncoding(val.Type(), opts); ok {
+			s = encodeBytes(val, enc)
+			break
 		}
+		s, err = e.marshalSlice(val, child, opts)
 	default:
-		var ok bool
-		s, ok = e.stringify(val, child)
-		if !ok {
-			return nil, false
-		}
+		s, err = e.stringify(val, child, opts)
 	}
-	return append(cols, s), true
+	if err != nil {
+		return nil, err
+	}
+	return []string{s}, nil
+}
+
+// marshal struct field data into a slice.  Fields of unsupported kinds are
+// skipped.  If a field can't be encoded, a *FieldError is returned; unless
+// the ErrorPolicy is PlaceholderOnError, in which case the placeholder is
+// used for the field and the data is returned along with a MultiError.
+func (e *Encoder) marshalStruct(str interface{}, child bool) ([]string, error) {
+	cols, _, err := e.marshalFields(reflect.ValueOf(str), child)
+	return cols, err
 }
 
-// marshal struct field data into a slice.
-func (e *Encoder) marshalStruct(str interface{}, child bool) ([]string, bool) {
+// marshalFields marshals the fields of val, a struct, as marshalStruct does.
+// It also returns which of the columns got the placeholder, or nil if none
+// did.
+func (e *Encoder) marshalFields(val reflect.Value, child bool) ([]string, []bool, error) {
 	var cols []string
-	val := reflect.ValueOf(str)
-	typ := reflect.TypeOf(str)
+	var placeholders []bool
+	var errs MultiError
+	typ := val.Type()
 	for i := 0; i < typ.NumField(); i++ {
 		tF := typ.Field(i)
 		if len(tF.PkgPath) > 0 {
 			continue
 		}
-		name := e.getFieldName(tF)
+		name, opts := e.getFieldName(tF)
 		if name == "" {
 			continue
 		}
 		vF := val.Field(i)
-		tmp, ok := e.marshal(vF, child)
-		if !ok {
-			// wasn't a supported kind, skip
-			continue
+		var tmp []string
+		var ph []bool // which of tmp got the placeholder
+		var err error
+		if opts.formatter != "" {
+			var s string
+			s, err = e.encodeFormatter(vF, opts)
+			tmp = []string{s}
+		} else if e.isCyclicPtr(tF.Type) {
+			var s string
+			s, err = e.marshalCyclic(vF, opts)
+			tmp = []string{s}
+		} else if sv, ok := e.nestedStruct(vF); ok {
+			tmp, ph, err = e.marshalFields(sv, true)
+		} else {
+			tmp, err = e.marshal(vF, child, opts)
+		}
+		if err != nil {
+			if err == errUnsupportedKind {
+				// wasn't a supported kind, skip
+				continue
+			}
+			err = fieldError(err, tF.Name, vF.Type())
+			if e.policy != PlaceholderOnError {
+				return nil, nil, err
+			}
+			errs = appendErrors(errs, err)
+			// nested structs have already used placeholders for
+			// their fields.
+			if ph == nil {
+				tmp, ph = []string{e.placeholder}, []bool{true}
+			}
+		}
+		if ph != nil && placeholders == nil {
+			placeholders = make([]bool, len(cols), len(cols)+len(tmp))
+		}
+		if placeholders != nil {
+			if ph == nil {
+				ph = make([]bool, len(tmp))
+			}
+			placeholders = append(placeholders, ph...)
 		}
 		cols = append(cols, tmp...)
 	}
-	return cols, true
+	if len(errs) > 0 {
+		return cols, placeholders, errs
+	}
+	return cols, placeholders, nil
+}
+
+// nestedStruct returns the struct whose fields are the columns of val, a
+// struct field, following pointers.  False is returned if val isn't a struct,
+// or a non-nil pointer to one, that is flattened.
+func (e *Encoder) nestedStruct(val reflect.Value) (reflect.Value, bool) {
+	if e.isLeaf(val.Type()) {
+		return reflect.Value{}, false
+	}
+	for val.Kind() == reflect.Ptr {
+		if val.IsNil() {
+			return reflect.Value{}, false
+		}
+		val = val.Elem()
+	}
+	return val, val.Kind() == reflect.Struct
+}
+
+// isCyclicPtr returns whether or not typ is a pointer to a struct that can
+// contain itself, which is encoded as a single column.
+func (e *Encoder) isCyclicPtr(typ reflect.Type) bool {
+	if typ.Kind() != reflect.Ptr {
+		return false
+	}
+	st, ok := e.ptrStruct(typ)
+	return ok && e.cyclic(st)
+}
+
+// marshalCyclic returns the value of val, a pointer to a struct that can
+// contain itself, as a single column: the struct's fields are a comma
+// separated list, enclosed in the separators, as they are for structs in
+// slices.  A nil pointer is NULL.
+func (e *Encoder) marshalCyclic(val reflect.Value, opts fieldOptions) (string, error) {
+	for val.Kind() == reflect.Ptr {
+		if val.IsNil() {
+			return e.null, nil
+		}
+		val = val.Elem()
+	}
+	return e.stringify(val, true, opts)
 }
 
 // marshal map handles marshalling of maps.  Both the key and value types must
 // be supported Kinds.
-func (e *Encoder) marshalMap(m reflect.Value, child bool) (string, bool) {
-	var ok bool
-	if ok = supportedBaseKind(m); !ok {
-		return "", false
+func (e *Encoder) marshalMap(m reflect.Value, child bool, opts fieldOptions) (string, error) {
+	if !supportedBaseKind(m) {
+		return "", errUnsupportedKind
 	}
 	// get the kind of the map value
 	var row string
@@ -329,9 +623,9 @@ func (e *Encoder) marshalMap(m reflect.Value, child bool) (string, bool) {
 	sort.Sort(sv)
 	for i, key := range sv {
 		val := m.MapIndex(key)
-		kk, ok := e.marshal(key, true)
-		if !ok {
-			return "", false
+		kk, err := e.marshal(key, true, opts)
+		if err != nil {
+			return "", fieldError(err, fmt.Sprintf("[%v]", key), key.Type())
 		}
 		var kval, vval string
 		for j, tmp := range kk {
@@ -343,9 +637,9 @@ func (e *Encoder) marshalMap(m reflect.Value, child bool) (string, bool) {
 		if len(kk) > 1 {
 			kval = fmt.Sprintf("%s%s%s", e.sepBeg, kval, e.sepEnd)
 		}
-		vv, ok := e.marshal(val, true)
-		if !ok {
-			return "", false
+		vv, err := e.marshal(val, true, opts)
+		if err != nil {
+			return "", fieldError(err, fmt.Sprintf("[%v]", key), val.Type())
 		}
 		for j, tmp := range vv {
 			if j > 0 && j < len(vv) {
@@ -366,24 +660,22 @@ func (e *Encoder) marshalMap(m reflect.Value, child bool) (string, bool) {
 	if child {
 		row = fmt.Sprintf("%s%s%s", e.sepBeg, row, e.sepEnd)
 	}
-	return row, true
+	return row, nil
 }
 
 // marshalSlice handles marshaling of slices. This should not receive a
 // pointer. Is is assumed that any pointers to the slice have already been
 // dereferenced.
-func (e *Encoder) marshalSlice(val reflect.Value, child bool) (string, bool) {
-	var ok bool
-	if ok = supportedBaseKind(val); !ok {
-		return "", false
+func (e *Encoder) marshalSlice(val reflect.Value, child bool, opts fieldOptions) (string, error) {
+	if !supportedBaseKind(val) {
+		return "", errUnsupportedKind
 	}
-	var sl, str string
+	var sl string
 	// check the type of slice and handle
 	for j := 0; j < val.Len(); j++ {
-		str = ""
-		str, ok = e.stringify(val.Index(j), child)
-		if !ok {
-			return "", false
+		str, err := e.stringify(val.Index(j), child, opts)
+		if err != nil {
+			return "", fieldError(err, fmt.Sprintf("[%d]", j), val.Index(j).Type())
 		}
 		if j == 0 {
 			sl = str
@@ -394,47 +686,179 @@ func (e *Encoder) marshalSlice(val reflect.Value, child bool) (string, bool) {
 	if child {
 		sl = fmt.Sprintf("%s%s%s", e.sepBeg, sl, e.sepEnd)
 	}
-	return sl, true
+	return sl, nil
 }
 
-// stringify takes a interface and returns the value it contains as a string
-// and true.  Composite types will first be marshaled.  If the received Kind is
-// not supported, and empty string and false will be returned.
-func (e *Encoder) stringify(v reflect.Value, child bool) (string, bool) {
+// stringify takes a interface and returns the value it contains as a string.
+// Composite types will first be marshaled.  If the received Kind is not
+// supported, errUnsupportedKind will be returned.
+func (e *Encoder) stringify(v reflect.Value, child bool, opts fieldOptions) (string, error) {
+	s, ok, err := e.encodeLeaf(v, child, opts)
+	if ok || err != nil {
+		return s, err
+	}
 	if !isSupportedKind(v.Kind()) {
-		return "", false
+		return "", errUnsupportedKind
 	}
 	switch v.Kind() {
 	case reflect.Bool:
-		return strconv.FormatBool(v.Bool()), true
+		if e.boolFmt != nil {
+			return e.boolFmt.FormatBool(v.Bool()), nil
+		}
+		return strconv.FormatBool(v.Bool()), nil
 	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
-		return strconv.Itoa(int(v.Int())), true
+		i := v.Int()
+		if i < 0 {
+			// the magnitude of the minimum int64 can't be stored in an int64.
+			return e.formatInteger(true, uint64(-(i+1))+1, e.intBase, opts), nil
+		}
+		return e.formatInteger(false, uint64(i), e.intBase, opts), nil
 	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
-		return strconv.FormatUint(uint64(v.Uint()), e.base), true
+		return e.formatInteger(false, v.Uint(), e.uintBase, opts), nil
 	case reflect.Float32:
-		return strconv.FormatFloat(v.Float(), 'E', -1, 32), true
+		return e.formatFloat(v.Float(), 32, opts), nil
 	case reflect.Float64:
-		return strconv.FormatFloat(v.Float(), 'E', -1, 64), true
+		return e.formatFloat(v.Float(), 64, opts), nil
 	case reflect.Complex64, reflect.Complex128:
-		return fmt.Sprintf("%g", v.Complex()), true
+		return fmt.Sprintf("%g", v.Complex()), nil
 	case reflect.String:
-		return v.String(), true
+		return v.String(), nil
 	case reflect.Ptr:
-		return e.stringify(v.Elem(), child)
+		return e.stringify(v.Elem(), child, opts)
 	default:
-		cols, ok := e.marshal(v, true)
-		if !ok {
-			return "", false
+		cols, err := e.marshal(v, true, opts)
+		if err != nil {
+			return "", err
 		}
 		r := cols[0]
 		for i := 1; i < len(cols); i++ {
 			r = fmt.Sprintf("%s,%s", r, cols[i])
 		}
 		if strings.HasPrefix(r, "(") {
-			return r, true
+			return r, nil
 		}
-		return fmt.Sprintf("%s%s%s", e.sepBeg, r, e.sepEnd), true
+		return fmt.Sprintf("%s%s%s", e.sepBeg, r, e.sepEnd), nil
+	}
+}
+
+// encodeLeaf encodes values that are encoded as a single value regardless
+// of their Kind: types with a registered EncodeFunc, types that implement
+// driver.Valuer, and time.Time, which is formatted using RFC 3339.  False is returned if val isn't one of these.
+func (e *Encoder) encodeLeaf(val reflect.Value, child bool, opts fieldOptions) (string, bool, error) {
+	if fn, ok := e.typeEncoder(val.Type()); ok {
+		s, err := fn(val)
+		return s, true, err
+	}
+	if isValuer(val.Type()) {
+		s, err := e.encodeValuer(val, child, opts)
+		return s, true, err
+	}
+	if val.Type() == timeType {
+		return val.Interface().(time.Time).Format(time.RFC3339Nano), true, nil
+	}
+	return "", false, nil
+}
+
+// bytesEncoding returns the BytesEncoding to use for values of type typ.
+// False is returned if typ isn't a slice or array of bytes, or if its bytes
+// should be encoded as a list.
+func (e *Encoder) bytesEncoding(typ reflect.Type, opts fieldOptions) (BytesEncoding, bool) {
+	if typ.Elem().Kind() != reflect.Uint8 {
+		return BytesList, false
+	}
+	enc := e.bytesEnc
+	if opts.hasBytesEnc {
+		enc = opts.bytesEnc
+	}
+	return enc, enc != BytesList
+}
+
+// encodeBytes returns the bytes of val, which must be a slice or array of
+// bytes, encoded using enc.
+func encodeBytes(val reflect.Value, enc BytesEncoding) string {
+	b := make([]byte, val.Len())
+	reflect.Copy(reflect.ValueOf(b), val)
+	switch enc {
+	case BytesHex:
+		return hex.EncodeToString(b)
+	case BytesBase64:
+		return base64.StdEncoding.EncodeToString(b)
+	}
+	return string(b)
+}
+
+// formatFloat returns the string representation of f.  The field's format
+// options take precedence over the Encoder's.  NaN and Inf values are
+// replaced with the Encoder's special values.
+func (e *Encoder) formatFloat(f float64, bitSize int, opts fieldOptions) string {
+	switch {
+	case math.IsNaN(f):
+		return e.nan
+	case math.IsInf(f, 1):
+		return e.posInf
+	case math.IsInf(f, -1):
+		return e.negInf
+	}
+	format, prec := e.floatFmt, e.prec
+	if opts.floatFmt != 0 {
+		format = opts.floatFmt
+	}
+	if opts.hasPrec {
+		prec = opts.prec
+	}
+	s := strconv.FormatFloat(f, format, prec, bitSize)
+	switch format {
+	case 'b', 'x', 'X':
+		return s
+	}
+	return e.formatNumber(s)
+}
+
+// formatInteger returns the string representation of an integer with the
+// magnitude u; neg is used for negative values.  The field's base takes
+// precedence over the received base.  If the field has the prefix option,
+// the base's prefix, e.g. 0x, is prepended to the digits.  If the field has
+// the digits option, the digits are zero padded to that width.  The
+// NumberFormatter is only applied to base 10 values.
+func (e *Encoder) formatInteger(neg bool, u uint64, base int, opts fieldOptions) string {
+	if opts.base != 0 {
+		base = opts.base
+	}
+	s := strconv.FormatUint(u, base)
+	if len(s) < opts.digits {
+		s = strings.Repeat("0", opts.digits-len(s)) + s
 	}
+	if opts.prefix {
+		s = basePrefix(base) + s
+	}
+	if neg {
+		s = "-" + s
+	}
+	if base == 10 {
+		return e.formatNumber(s)
+	}
+	return s
+}
+
+// basePrefix returns the Go literal prefix for the base, if it has one.
+func basePrefix(base int) string {
+	switch base {
+	case 2:
+		return "0b"
+	case 8:
+		return "0o"
+	case 16:
+		return "0x"
+	}
+	return ""
+}
+
+// formatNumber applies the NumberFormatter, if there is one, to s.
+func (e *Encoder) formatNumber(s string) string {
+	if e.numFmt == nil {
+		return s
+	}
+	return e.numFmt.FormatNumber(s)
 }
 
 // ptrKind returns the Kind that it points to; if it's another pointer, this
@@ -468,7 +892,13 @@ func baseKind(typ reflect.Type) (k, v reflect.Kind) {
 }
 
 func supportedBaseKind(val reflect.Value) bool {
-	k, v := baseKind(val.Type())
+	return supportedBaseType(val.Type())
+}
+
+// supportedBaseType returns whether or not the base kind(s) of typ are
+// supported.
+func supportedBaseType(typ reflect.Type) bool {
+	k, v := baseKind(typ)
 	if !isSupportedKind(k) {
 		return false
 	}
@@ -483,7 +913,7 @@ func supportedBaseKind(val reflect.Value) bool {
 }
 
 // sliceKind returns the Kind of the slice; e.g. reflect.Slice will be
-//returned for [][]*int.
+// returned for [][]*int.
 func sliceKind(val reflect.Value) reflect.Kind {
 	switch val.Type().Elem().Kind() {
 	case reflect.Ptr:
@@ -527,20 +957,43 @@ func isSupportedKind(k reflect.Kind) bool {
 	return true
 }
 
-// getFieldName gets the field name.  If field tags are being used and the field
-// is tagged with -, or skip this field, an empty string will be returned;
-// which is a signal to skip this field.
-func (e *Encoder) getFieldName(field reflect.StructField) string {
-	if e.useTags {
-		name := field.Tag.Get(e.tag)
-		// skip columns tagged with -
-		if name == "-" {
-			return ""
-		}
-		if name != "" {
-			return name
-		}
+// getFieldName gets the field name and any options set in the field's tag.
+// If field tags are being used and the field is tagged with -, or skip this
+// field, an empty string will be returned; which is a signal to skip this
+// field.
+//
+// The options come from the tag set with SetTag, or the first tag set with
+// SetTags.  The name comes from the first tag, in order, that has a name; if
+// none of them do, the field's name, transformed by the name mapper, if any,
+// is used.
+func (e *Encoder) getFieldName(field reflect.StructField) (string, fieldOptions) {
+	if !e.useTags {
+		return e.mapName(field.Name), fieldOptions{}
+	}
+	name, opts := parseTag(field.Tag.Get(e.tag))
+	if label := field.Tag.Get(labelTag); label != "" {
+		opts.label = label
 	}
-	return field.Name
+	opts.doc = field.Tag.Get(docTag)
+	for i := 0; name == "" && i < len(e.fallbacks); i++ {
+		// only the name is used; options, e.g. json's omitempty, are
+		// ignored.
+		name, _, _ = strings.Cut(field.Tag.Get(e.fallbacks[i]), ",")
+	}
+	// skip columns tagged with -
+	if name == "-" {
+		return "", opts
+	}
+	if name != "" {
+		return name, opts
+	}
+	return e.mapName(field.Name), opts
+}
 
+// mapName returns the name transformed by the name mapper, if there is one.
+func (e *Encoder) mapName(name string) string {
+	if e.nameMapper == nil {
+		return name
+	}
+	return e.nameMapper(name)
 }
@@ -2,6 +2,7 @@ package struct2csv
 
 import (
 	"fmt"
+	"math"
 	"reflect"
 	"sort"
 	"strings"
@@ -346,9 +347,9 @@ func TestIgnoreTags(t *testing.T) {
 	if len(names) > 0 {
 		t.Errorf("expected no column names, got %v", names)
 	}
-	vals, ok := enc.marshalStruct(ts, false)
-	if !ok {
-		t.Errorf("expected true got %t", ok)
+	vals, err := enc.marshalStruct(ts, false)
+	if err != nil {
+		t.Errorf("unexpected error: %s", err)
 	}
 	if len(vals) != 0 {
 		t.Errorf("expected no column values, got %v", vals)
@@ -892,7 +893,7 @@ func TestPrivateMarshal(t *testing.T) {
 	tsts[11].val = [][]*int{[]*int{i, i}, []*int{i}}
 	enc := New()
 	for i, tst := range tsts {
-		cols, _ := enc.marshal(reflect.ValueOf(tst.val), false)
+		cols, _ := enc.marshal(reflect.ValueOf(tst.val), false, fieldOptions{})
 		if len(cols) != len(tst.expCols) {
 			t.Errorf("%d: expected marshal to result in %d rows, got %d", i, len(tst.expCols), len(cols))
 			continue
@@ -1004,3 +1005,312 @@ func splitCleanSort(s string) []string {
 	sort.Strings(parts)
 	return parts
 }
+
+type Prices struct {
+	Name  string
+	Price float64 `csv:"price,format=f,prec=2"`
+	Rate  float32 `csv:"rate,prec=3"`
+	Qty   float64
+}
+
+func TestFloatFormat(t *testing.T) {
+	tsts := []struct {
+		format   byte
+		prec     int
+		specials []string
+		val      Prices
+		expected []string
+	}{
+		{0, 0, nil, Prices{"a", 1.5, 0.25, 2}, []string{"a", "1.50", "2.500E-01", "2E+00"}},
+		{'f', -1, nil, Prices{"b", 1.005, 0.5, 2.25}, []string{"b", "1.00", "0.500", "2.25"}},
+		{'g', 2, nil, Prices{"c", 10, 1.5, 1234.5}, []string{"c", "10.00", "1.5", "1.2e+03"}},
+		{'z', 2, nil, Prices{"d", 10, 1.5, 1.5}, []string{"d", "10.00", "1.500E+00", "1.5E+00"}},
+		{'f', -1, nil, Prices{"e", math.NaN(), float32(math.Inf(1)), math.Inf(-1)}, []string{"e", "NaN", "+Inf", "-Inf"}},
+		{'f', -1, []string{"", "inf", "-inf"}, Prices{"f", math.NaN(), float32(math.Inf(1)), math.Inf(-1)}, []string{"f", "", "inf", "-inf"}},
+	}
+	for i, tst := range tsts {
+		enc := New()
+		if tst.format != 0 {
+			enc.SetFloatFormat(tst.format, tst.prec)
+		}
+		if tst.specials != nil {
+			enc.SetFloatSpecials(tst.specials[0], tst.specials[1], tst.specials[2])
+		}
+		row, err := enc.GetRow(tst.val)
+		if err != nil {
+			t.Errorf("%d: unexpected error: %s", i, err)
+			continue
+		}
+		if len(row) != len(tst.expected) {
+			t.Errorf("%d: expected %d cols, got %d", i, len(tst.expected), len(row))
+			continue
+		}
+		for j, v := range row {
+			if v != tst.expected[j] {
+				t.Errorf("%d:%d: expected %q, got %q", i, j, tst.expected[j], v)
+			}
+		}
+	}
+}
+
+type Flags struct {
+	Int   int64
+	Uint  uint64
+	Flags uint16 `csv:"flags,base=16,prefix,digits=4"`
+	Mode  int    `csv:"mode,base=8,prefix"`
+	Bits  uint8  `csv:"bits,base=2,digits=8"`
+}
+
+func TestIntFormat(t *testing.T) {
+	tsts := []struct {
+		intBase  int
+		uintBase int
+		val      Flags
+		expected []string
+	}{
+		{10, 10, Flags{math.MaxInt64, math.MaxUint64, 0xff, 0755, 5},
+			[]string{"9223372036854775807", "18446744073709551615", "0x00ff", "0o755", "00000101"}},
+		{10, 10, Flags{math.MinInt64, 0, 0xbeef, -8, 255},
+			[]string{"-9223372036854775808", "0", "0xbeef", "-0o10", "11111111"}},
+		{16, 10, Flags{-255, 255, 1, 1, 1},
+			[]string{"-ff", "255", "0x0001", "0o1", "00000001"}},
+		{10, 2, Flags{-255, 5, 1, 1, 1},
+			[]string{"-255", "101", "0x0001", "0o1", "00000001"}},
+	}
+	for i, tst := range tsts {
+		enc := New()
+		enc.SetIntBase(tst.intBase)
+		enc.SetUintBase(tst.uintBase)
+		row, err := enc.GetRow(tst.val)
+		if err != nil {
+			t.Errorf("%d: unexpected error: %s", i, err)
+			continue
+		}
+		if len(row) != len(tst.expected) {
+			t.Errorf("%d: expected %d cols, got %d", i, len(tst.expected), len(row))
+			continue
+		}
+		for j, v := range row {
+			if v != tst.expected[j] {
+				t.Errorf("%d:%d: expected %q, got %q", i, j, tst.expected[j], v)
+			}
+		}
+	}
+}
+
+func TestSetBase(t *testing.T) {
+	enc := New()
+	if enc.intBase != 10 || enc.uintBase != 10 {
+		t.Errorf("expected default bases to be 10, got %d and %d", enc.intBase, enc.uintBase)
+	}
+	enc.SetBase(16)
+	if enc.intBase != 16 || enc.uintBase != 16 {
+		t.Errorf("expected bases to be 16, got %d and %d", enc.intBase, enc.uintBase)
+	}
+	enc.SetBase(0)
+	if enc.intBase != 2 || enc.uintBase != 2 {
+		t.Errorf("expected bases to be 2, got %d and %d", enc.intBase, enc.uintBase)
+	}
+	enc.SetIntBase(64)
+	if enc.intBase != 36 {
+		t.Errorf("expected int base to be 36, got %d", enc.intBase)
+	}
+}
+
+type Blobs struct {
+	Data   []byte
+	Hash   [4]byte `csv:"hash,hex"`
+	Blob   []byte  `csv:"blob,base64"`
+	Raw    []byte  `csv:"raw,raw"`
+	List   []byte  `csv:"list,list"`
+	Hashes [][]byte
+}
+
+func TestBytesEncoding(t *testing.T) {
+	val := Blobs{
+		Data:   []byte{1, 2, 255},
+		Hash:   [4]byte{0xde, 0xad, 0xbe, 0xef},
+		Blob:   []byte("hello"),
+		Raw:    []byte("hi"),
+		List:   []byte{1, 2},
+		Hashes: [][]byte{[]byte{0xab}, []byte{0xcd}},
+	}
+	tsts := []struct {
+		enc      BytesEncoding
+		expected []string
+	}{
+		{BytesList, []string{"1,2,255", "deadbeef", "aGVsbG8=", "hi", "1,2", "(171),(205)"}},
+		{BytesHex, []string{"0102ff", "deadbeef", "aGVsbG8=", "hi", "1,2", "(ab),(cd)"}},
+		{BytesBase64, []string{"AQL/", "deadbeef", "aGVsbG8=", "hi", "1,2", "(qw==),(zQ==)"}},
+	}
+	for i, tst := range tsts {
+		enc := New()
+		enc.SetBytesEncoding(tst.enc)
+		row, err := enc.GetRow(val)
+		if err != nil {
+			t.Errorf("%d: unexpected error: %s", i, err)
+			continue
+		}
+		if len(row) != len(tst.expected) {
+			t.Errorf("%d: expected %d cols, got %d: %v", i, len(tst.expected), len(row), row)
+			continue
+		}
+		for j, v := range row {
+			if v != tst.expected[j] {
+				t.Errorf("%d:%d: expected %q, got %q", i, j, tst.expected[j], v)
+			}
+		}
+	}
+}
+
+type Account struct {
+	CustID  int    `csv:"cust_id" csvlabel:"Customer ID"`
+	Name    string `csv:"name,label=Customer Name"`
+	Balance float64
+	Owner   struct {
+		First string `csvlabel:"First Name"`
+	} `csv:"owner" csvlabel:"Account Owner"`
+}
+
+func TestHeaderMode(t *testing.T) {
+	names := []string{"cust_id", "name", "Balance", "First"}
+	labels := []string{"Customer ID", "Customer Name", "Balance", "First Name"}
+	enc := New()
+	cols, err := enc.GetColNames(Account{})
+	if err != nil {
+		t.Errorf("unexpected error: %s", err)
+	}
+	if !reflect.DeepEqual(cols, names) {
+		t.Errorf("expected %q, got %q", names, cols)
+	}
+	if !reflect.DeepEqual(enc.ColLabels(), labels) {
+		t.Errorf("expected labels %q, got %q", labels, enc.ColLabels())
+	}
+	enc.SetHeaderMode(HeaderLabels)
+	rows, err := enc.Marshal([]Account{Account{CustID: 1}})
+	if err != nil {
+		t.Errorf("unexpected error: %s", err)
+	}
+	if !reflect.DeepEqual(rows[0], labels) {
+		t.Errorf("expected %q, got %q", labels, rows[0])
+	}
+	if !reflect.DeepEqual(enc.ColNames(), names) {
+		t.Errorf("expected names %q, got %q", names, enc.ColNames())
+	}
+	hdr := headerRows(enc.columns(reflect.TypeOf(Account{}), nil, nil), HeaderGrouped, HeaderLabels)
+	expected := [][]string{[]string{"", "", "", "Account Owner"}, labels}
+	if !reflect.DeepEqual(hdr, expected) {
+		t.Errorf("expected %q, got %q", expected, hdr)
+	}
+}
+
+type Record struct {
+	ID        int    `csv:"id" json:"record_id" db:"rid"`
+	Name      string `json:"name,omitempty" db:"full_name"`
+	Email     string `json:",omitempty" db:"email_address"`
+	Secret    string `json:"-" db:"secret"`
+	Note      string `csv:"note,label=Notes" json:"-"`
+	CreatedAt string
+}
+
+func TestSetTags(t *testing.T) {
+	tsts := []struct {
+		tags     []string
+		useTags  bool
+		mapper   func(string) string
+		expected []string
+	}{
+		{[]string{"csv"}, true, nil, []string{"id", "Name", "Email", "Secret", "note", "CreatedAt"}},
+		{[]string{"csv", "json"}, true, nil, []string{"id", "name", "Email", "note", "CreatedAt"}},
+		{[]string{"csv", "json", "db"}, true, nil, []string{"id", "name", "email_address", "note", "CreatedAt"}},
+		{[]string{"db", "", "csv"}, true, nil, []string{"rid", "full_name", "email_address", "secret", "note", "CreatedAt"}},
+		{[]string{"json", "csv"}, true, strings.ToLower, []string{"record_id", "name", "email", "createdat"}},
+		{[]string{"csv", "json"}, false, strings.ToUpper, []string{"ID", "NAME", "EMAIL", "SECRET", "NOTE", "CREATEDAT"}},
+	}
+	for i, tst := range tsts {
+		enc := New()
+		enc.SetTags(tst.tags...)
+		enc.SetUseTags(tst.useTags)
+		enc.SetNameMapper(tst.mapper)
+		cols, err := enc.GetColNames(Record{})
+		if err != nil {
+			t.Errorf("%d: unexpected error: %s", i, err)
+			continue
+		}
+		if !reflect.DeepEqual(cols, tst.expected) {
+			t.Errorf("%d: expected %q, got %q", i, tst.expected, cols)
+		}
+	}
+	// SetTag removes the fallbacks
+	enc := New()
+	enc.SetTags("csv", "json")
+	enc.SetTag("db")
+	if enc.tag != "db" || len(enc.fallbacks) != 0 {
+		t.Errorf("expected tag db with no fallbacks, got %q and %q", enc.tag, enc.fallbacks)
+	}
+}
+
+type Node struct {
+	Name string
+	Next *Node
+}
+
+type Site struct {
+	Name string
+	Addr *Address
+	Node *Node
+}
+
+func TestStructPointer(t *testing.T) {
+	enc := New()
+	enc.SetNullToken("NULL")
+	sites := []Site{
+		Site{Name: "a", Addr: &Address{Addr1: "1 Main", City: "Springfield"}, Node: &Node{Name: "n", Next: &Node{}}},
+		Site{Name: "b"},
+	}
+	// Node can nest itself, so its columns depend on the data; it's a
+	// single column
+	expected := [][]string{
+		[]string{"Name", "Addr1", "Addr2", "City", "State", "Zip", "Node"},
+		[]string{"a", "1 Main", "", "Springfield", "", "", "(n,(,NULL))"},
+		[]string{"b", "NULL", "NULL", "NULL", "NULL", "NULL", "NULL"},
+	}
+	rows, err := enc.Marshal(sites)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if !reflect.DeepEqual(rows, expected) {
+		t.Errorf("expected %q, got %q", expected, rows)
+	}
+	hdr, typed, err := enc.typedData(sites)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if len(hdr) != 7 || len(typed) != 2 {
+		t.Fatalf("expected 7 columns and 2 rows, got %d and %d", len(hdr), len(typed))
+	}
+	for i, v := range typed[1][1:] {
+		if v != nil {
+			t.Errorf("column %d: expected nil, got %#v", i+1, v)
+		}
+	}
+	// a linked list
+	nodes := []Node{
+		Node{Name: "1", Next: &Node{Name: "2", Next: &Node{Name: "3"}}},
+		Node{Name: "2", Next: &Node{Name: "3"}},
+		Node{Name: "3"},
+	}
+	expected = [][]string{
+		[]string{"Name", "Next"},
+		[]string{"1", "(2,(3,NULL))"},
+		[]string{"2", "(3,NULL)"},
+		[]string{"3", "NULL"},
+	}
+	rows, err = enc.Marshal(nodes)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if !reflect.DeepEqual(rows, expected) {
+		t.Errorf("expected %q, got %q", expected, rows)
+	}
+}
@@ -0,0 +1,119 @@
+package struct2csv
+
+import (
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// fieldOptions are the options set on a field using its tag, e.g.
+// `csv:"price,format=f,prec=2"`.  Zero values mean that the Encoder's
+// setting should be used.
+type fieldOptions struct {
+	floatFmt byte // format for strconv.FormatFloat
+	prec     int  // precision for strconv.FormatFloat
+	hasPrec  bool // whether prec was set; 0 is a valid precision
+	base     int  // base for integers
+	prefix   bool // whether integers get a base prefix, e.g. 0x
+	digits   int  // minimum number of digits for integers; zero padded
+
+	bytesEnc    BytesEncoding // encoding for []byte and [N]byte
+	hasBytesEnc bool          // whether bytesEnc was set; BytesList is 0
+
+	formatter string // name of the registered formatter to use
+	label     string // display label for the header
+	doc       string // description of the column, for schemas
+
+	width int   // width of the field in fixed-width output
+	align Align // alignment of the field in fixed-width output
+	pad   rune  // padding of the field in fixed-width output
+}
+
+const (
+	// labelTag is the tag used for a field's display label.
+	labelTag = "csvlabel"
+	// docTag is the tag used for a field's description.
+	docTag = "csvdoc"
+)
+
+// parseTag splits a field's tag value into its name and its options.  The
+// name is everything up to the first comma; the rest of the tag is a comma
+// separated list of options.  Options that aren't recognized, or have invalid
+// values, are ignored.
+func parseTag(tag string) (string, fieldOptions) {
+	var opts fieldOptions
+	name, rest, _ := strings.Cut(tag, ",")
+	for rest != "" {
+		var opt string
+		opt, rest, _ = strings.Cut(rest, ",")
+		key, val, _ := strings.Cut(opt, "=")
+		switch strings.TrimSpace(key) {
+		case "format":
+			val = strings.TrimSpace(val)
+			if len(val) == 1 && isFloatFormat(val[0]) {
+				opts.floatFmt = val[0]
+			}
+		case "prec":
+			i, err := strconv.Atoi(strings.TrimSpace(val))
+			if err != nil {
+				continue
+			}
+			opts.prec = i
+			opts.hasPrec = true
+		case "base":
+			i, err := strconv.Atoi(strings.TrimSpace(val))
+			if err != nil || i < 2 || i > 36 {
+				continue
+			}
+			opts.base = i
+		case "label":
+			opts.label = strings.TrimSpace(val)
+		case "fmt":
+			opts.formatter = strings.TrimSpace(val)
+		case "prefix":
+			opts.prefix = true
+		case "list":
+			opts.bytesEnc, opts.hasBytesEnc = BytesList, true
+		case "hex":
+			opts.bytesEnc, opts.hasBytesEnc = BytesHex, true
+		case "base64":
+			opts.bytesEnc, opts.hasBytesEnc = BytesBase64, true
+		case "raw":
+			opts.bytesEnc, opts.hasBytesEnc = BytesRaw, true
+		case "width":
+			i, err := strconv.Atoi(strings.TrimSpace(val))
+			if err != nil || i < 1 {
+				continue
+			}
+			opts.width = i
+		case "align":
+			switch strings.TrimSpace(val) {
+			case "left":
+				opts.align = AlignLeft
+			case "right":
+				opts.align = AlignRight
+			}
+		case "pad":
+			if utf8.RuneCountInString(val) == 1 {
+				opts.pad, _ = utf8.DecodeRuneInString(val)
+			}
+		case "digits":
+			i, err := strconv.Atoi(strings.TrimSpace(val))
+			if err != nil || i < 0 {
+				continue
+			}
+			opts.digits = i
+		}
+	}
+	return name, opts
+}
+
+// isFloatFormat returns whether or not b is a format supported by
+// strconv.FormatFloat.
+func isFloatFormat(b byte) bool {
+	switch b {
+	case 'b', 'e', 'E', 'f', 'g', 'G', 'x', 'X':
+		return true
+	}
+	return false
+}
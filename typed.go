@@ -0,0 +1,141 @@
+package struct2csv
+
+import (
+	"database/sql/driver"
+	"math"
+	"reflect"
+	"strconv"
+	"time"
+)
+
+// typedData returns the column names of v, a slice of structs, and the
+// values of each struct's columns as their types: nil for NULL values, bool,
+// int64, uint64, float64, time.Time, or, for everything else, the string
+// encoded by the Encoder.  Numbers aren't affected by the Encoder's number
+// formatting settings.  Errors are handled according to the ErrorPolicy,
+// as they are by Marshal.
+func (e *Encoder) typedData(v interface{}) ([]string, [][]interface{}, error) {
+	val, err := structSlice(v)
+	if err != nil {
+		return nil, nil, err
+	}
+	hdr := e.getColNames(val.Index(0).Interface())
+	cols := e.columns(val.Type().Elem(), nil, nil)
+	var rows [][]interface{}
+	var errs MultiError
+	for i := 0; i < val.Len(); i++ {
+		row, err := e.typedRow(val.Index(i), cols)
+		if err != nil {
+			// the header is row 0
+			err = rowError(err, i, len(rows)+1)
+			if e.policy == AbortOnError || !isEncodingError(err) {
+				return nil, nil, err
+			}
+			errs = appendErrors(errs, err)
+		}
+		if row == nil {
+			continue
+		}
+		rows = append(rows, row)
+	}
+	if len(errs) > 0 {
+		return hdr, rows, errs
+	}
+	return hdr, rows, nil
+}
+
+// typedRow returns the values of the columns of val, a struct.  If a field
+// can't be encoded, the error is returned, along with the row if the
+// ErrorPolicy is PlaceholderOnError.
+func (e *Encoder) typedRow(val reflect.Value, cols []column) ([]interface{}, error) {
+	strs, placeholders, err := e.marshalFields(val, false)
+	if strs == nil {
+		return nil, err
+	}
+	if len(strs) != len(cols) {
+		return nil, FieldCountError{Expected: len(cols), Got: len(strs)}
+	}
+	row := make([]interface{}, len(strs))
+	for i, col := range cols {
+		if placeholders != nil && placeholders[i] {
+			row[i] = strs[i]
+			continue
+		}
+		fv, ok := fieldByIndex(val, col.index)
+		if !ok {
+			// nested in a nil struct pointer
+			row[i] = nil
+			continue
+		}
+		row[i] = e.typedValue(fv, col, strs[i])
+	}
+	return row, err
+}
+
+// typedValue returns the value of val, a column's field, as its type; s is
+// the value encoded as a string.
+func (e *Encoder) typedValue(val reflect.Value, col column, s string) interface{} {
+	if col.opts.formatter != "" {
+		return s
+	}
+	for {
+		if _, ok := e.typeEncoder(val.Type()); ok {
+			return s
+		}
+		if val.Kind() == reflect.Ptr && val.IsNil() {
+			return nil
+		}
+		if isValuer(val.Type()) {
+			return typedValuer(val, s)
+		}
+		if val.Kind() != reflect.Ptr {
+			break
+		}
+		val = val.Elem()
+	}
+	switch val.Kind() {
+	case reflect.Bool:
+		return val.Bool()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return val.Int()
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return val.Uint()
+	case reflect.Float32, reflect.Float64:
+		f := val.Float()
+		if math.IsNaN(f) || math.IsInf(f, 0) {
+			return s
+		}
+		if val.Kind() == reflect.Float32 {
+			// use the shortest decimal representation of the float32,
+			// e.g. 0.1 instead of 0.10000000149011612
+			f, _ = strconv.ParseFloat(strconv.FormatFloat(f, 'g', -1, 32), 64)
+		}
+		return f
+	case reflect.Struct:
+		if val.Type() == timeType {
+			return val.Interface().(time.Time)
+		}
+	}
+	return s
+}
+
+// typedValuer returns the value returned by the Value method of val, which
+// implements driver.Valuer, if it is one of the typed values.
+func typedValuer(val reflect.Value, s string) interface{} {
+	v, err := val.Interface().(driver.Valuer).Value()
+	if err != nil {
+		return s
+	}
+	switch v := v.(type) {
+	case nil:
+		return nil
+	case bool, int64, time.Time:
+		return v
+	case float64:
+		if math.IsNaN(v) || math.IsInf(v, 0) {
+			return s
+		}
+		return v
+	}
+	return s
+}
@@ -11,61 +11,394 @@
 package struct2csv
 
 import (
+	"bufio"
+	"bytes"
 	"encoding/csv"
+	"fmt"
 	"io"
+	"reflect"
+	"strings"
 )
 
-// A Writer writes structs to a CSV encoded file.  This wraps both `csv.Writer`
-// and this package's `Encoder`.
+// A Writer writes structs to a CSV encoded file.  This wraps this package's
+// `Encoder` and a writer, based on `csv.Writer`, that writes records using a
+// Dialect.
 type Writer struct {
-	e Encoder
-	w *csv.Writer
-	b int64
-	r int
+	e        Encoder
+	w        *recordWriter
+	out      io.Writer // the io.Writer passed to NewWriter
+	charset  Charset
+	repl     rune  // the replacement for unrepresentable runes; 0 for none
+	b        int64 // the number of bytes written to out
+	r        int
+	n        int // the number of structs the Writer has been given
+	onError  func(row int, err error) error
+	writeHdr bool // whether WriteStructs writes the header
+	hdrDone  bool // whether the header has been written
+	hdrStyle HeaderStyle
+	strict   bool         // whether writing structs fails if CheckDialect does
+	planType reflect.Type // the struct type that plan is for
+	plan     []column     // the columns of planType
+}
+
+// A HeaderMismatchError is returned when appending to CSV data whose header
+// doesn't match the column names of the structs being written.  For grouped
+// headers, Expected and Got are the first header row that doesn't match.
+type HeaderMismatchError struct {
+	Expected []string
+	Got      []string
+}
+
+func (e HeaderMismatchError) Error() string {
+	return fmt.Sprintf("struct2csv: header mismatch: expected %q, got %q", e.Expected, e.Got)
 }
 
 // NewWriter returns a new Writer that write to w.
 func NewWriter(w io.Writer) *Writer {
 	enc := New()
-	return &Writer{e: *enc, w: csv.NewWriter(w)}
+	wr := &Writer{e: *enc, out: w, writeHdr: true}
+	wr.w = newRecordWriter(byteCounter{wr})
+	return wr
+}
+
+// byteCounter is an io.Writer that writes to the Writer's output, counting
+// the bytes written.
+type byteCounter struct {
+	w *Writer
+}
+
+func (c byteCounter) Write(p []byte) (int, error) {
+	n, err := c.w.out.Write(p)
+	c.w.b += int64(n)
+	return n, err
 }
 
-// WriteColNames writes out the column names of the CSV field.
+// WriteColNames writes out the column names of the CSV field.  If the
+// HeaderStyle is HeaderGrouped, multiple header rows may be written.
 func (w *Writer) WriteColNames(st interface{}) error {
 	cols, err := w.e.GetColNames(st)
 	if err != nil {
 		return err
 	}
-	w.r++
-	return w.w.Write(cols)
+	if w.hdrStyle == HeaderFlat {
+		return w.WriteHeader(cols)
+	}
+	for _, row := range headerRows(w.e.columns(reflect.TypeOf(st), nil, nil), w.hdrStyle, w.e.hdrMode) {
+		err = w.Write(row)
+		if err != nil {
+			return err
+		}
+	}
+	w.hdrDone = true
+	return nil
+}
+
+// SetHeaderStyle sets the style of the header written by WriteColNames and
+// WriteStructs.  By default, this is HeaderFlat, a single row of column
+// names.  HeaderGrouped writes one row per level of struct nesting, with
+// the names of the struct fields above their columns.
+func (w *Writer) SetHeaderStyle(style HeaderStyle) {
+	w.hdrStyle = style
+}
+
+// WriteHeader writes cols as the header row.  This can be used to supply
+// display names for the columns, instead of the column names.  Once a header
+// has been written, WriteStructs will not write another one.
+func (w *Writer) WriteHeader(cols []string) error {
+	err := w.Write(cols)
+	if err != nil {
+		return err
+	}
+	w.hdrDone = true
+	return nil
+}
+
+// SetWriteHeader sets whether or not WriteStructs writes the column names as
+// the first row.  By default, this is true.
+func (w *Writer) SetWriteHeader(b bool) {
+	w.writeHdr = b
+}
+
+// Append prepares the Writer for appending to existing CSV data in f, which
+// should also be what the Writer writes to.  The existing header, if there
+// is one, is validated against the column names of st, a struct; if they
+// don't match, a HeaderMismatchError is returned.  If custom display names
+// were used for the header, st can be the []string of those names instead.
+// With the HeaderGrouped style, each of the header rows of st is validated.
+// If f is empty, the header will be written as usual.
+//
+// After validation, f's offset is set to the end of the data, and a
+// newline is written if the data doesn't end with one.  Because the header
+// already exists, WriteStructs won't write another one.  The Writer must be
+// configured before calling Append, as its configuration affects the column
+// names.
+func (w *Writer) Append(f io.ReadWriteSeeker, st interface{}) error {
+	names, ok := st.([]string)
+	if !ok {
+		var err error
+		names, err = w.e.GetColNames(st)
+		if err != nil {
+			return err
+		}
+	}
+	expected := [][]string{names}
+	if !ok && w.hdrStyle == HeaderGrouped {
+		expected = headerRows(w.e.columns(reflect.TypeOf(st), nil, nil), w.hdrStyle, w.e.hdrMode)
+	}
+	_, err := f.Seek(0, io.SeekStart)
+	if err != nil {
+		return err
+	}
+	r := csv.NewReader(f)
+	r.Comma = w.w.Comma
+	r.FieldsPerRecord = -1
+	r.LazyQuotes = w.w.Quote != '"'
+	for i, row := range expected {
+		hdr, err := r.Read()
+		if err == io.EOF && i == 0 {
+			// nothing to append to; write the header as usual
+			_, err = f.Seek(0, io.SeekStart)
+			return err
+		}
+		if err != nil && err != io.EOF {
+			return err
+		}
+		if i == 0 {
+			// there is existing data, so don't write a BOM
+			w.w.bomDone = true
+			if len(hdr) > 0 {
+				hdr[0] = strings.TrimPrefix(hdr[0], "\uFEFF")
+			}
+		}
+		if !equalStrings(hdr, row) {
+			return HeaderMismatchError{Expected: row, Got: hdr}
+		}
+	}
+	// check whether or not the data ends with a newline
+	_, err = f.Seek(-1, io.SeekEnd)
+	if err != nil {
+		return err
+	}
+	var b [1]byte
+	_, err = io.ReadFull(f, b[:])
+	if err != nil {
+		return err
+	}
+	if b[0] != '\n' {
+		_, err = f.Write([]byte(w.newline()))
+		if err != nil {
+			return err
+		}
+	}
+	w.hdrDone = true
+	return nil
+}
+
+// newline returns the line terminator being used.
+func (w *Writer) newline() string {
+	if w.w.UseCRLF {
+		return "\r\n"
+	}
+	return "\n"
+}
+
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
 }
 
 // WriteStruct takes a struct, marshals it to CSV and writhes the CSV
-// record to the writer
+// record to the writer.  If a field can't be encoded, a *FieldError is
+// returned; its Row is the index of the record that was being written and
+// its Index is the number of structs the Writer was given before st.
+//
+// If an OnError func has been set, it is called with the error and its
+// return value is returned instead.  Otherwise, what happens depends on the
+// Encoder's ErrorPolicy: for AbortOnError and SkipOnError the struct isn't
+// written; for PlaceholderOnError it is written using placeholders.  In
+// either case, the error is returned.
 func (w *Writer) WriteStruct(st interface{}) error {
+	row, err := w.encodeRow(st, w.n)
+	w.n++
+	if row == nil {
+		return err
+	}
+	werr := w.writeRow(row, reflect.ValueOf(st))
+	if werr != nil {
+		return werr
+	}
+	return err
+}
+
+// encodeRow encodes st, the struct at index in the input, as a row.  If a
+// field can't be encoded, the error is returned, after the OnError func, if
+// any, has been called; the row is nil if it shouldn't be written.
+func (w *Writer) encodeRow(st interface{}, index int) ([]string, error) {
+	err := w.checkStrict()
+	if err != nil {
+		return nil, err
+	}
 	row, err := w.e.GetRow(st)
+	if err != nil {
+		err = rowError(err, index, w.r)
+		if w.onError != nil {
+			err = w.onError(index, err)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+	return row, err
+}
+
+// writeRow writes a row encoded from val, a struct.
+func (w *Writer) writeRow(row []string, val reflect.Value) error {
+	err := w.w.writeRecord(row, w.numericCols(val.Type()), w.nullCols(val))
 	if err != nil {
 		return err
 	}
 	w.r++
-	return w.w.Write(row)
+	return nil
+}
+
+// columns returns the columns of typ.
+func (w *Writer) columns(typ reflect.Type) []column {
+	if typ != w.planType {
+		w.plan = w.e.columns(typ, nil, nil)
+		w.planType = typ
+	}
+	return w.plan
+}
+
+// numericCols returns whether or not each column of typ is numeric, if the
+// QuotePolicy needs to know; otherwise nil is returned.
+func (w *Writer) numericCols(typ reflect.Type) []bool {
+	if w.w.quoting != QuoteNonNumeric {
+		return nil
+	}
+	cols := w.columns(typ)
+	numeric := make([]bool, len(cols))
+	for i, col := range cols {
+		numeric[i] = col.numeric()
+	}
+	return numeric
+}
+
+// nullCols returns whether or not each column of val, a struct, is NULL, if
+// the Dialect has a NULL token; otherwise nil is returned.  The values that
+// are NULL are nil pointers, including those of the structs a column is
+// nested in, and Valuers with nil values.
+func (w *Writer) nullCols(val reflect.Value) []bool {
+	if w.w.Null == "" {
+		return nil
+	}
+	cols := w.columns(val.Type())
+	nulls := make([]bool, len(cols))
+	for i, col := range cols {
+		fv, ok := fieldByIndex(val, col.index)
+		nulls[i] = !ok || w.e.typedValue(fv, col, "") == nil
+	}
+	return nulls
+}
+
+// rowSize returns the number of bytes a row encoded from val, a struct,
+// takes when written, after transcoding, as counted by Bytes.
+func (w *Writer) rowSize(row []string, val reflect.Value) int64 {
+	var b bytes.Buffer
+	var out io.Writer = &b
+	if w.charset != UTF8 {
+		t := newTranscoder(&b, w.charset, w.repl)
+		// the record isn't the start of the output
+		t.started = true
+		out = t
+	}
+	rw := *w.w
+	rw.w = bufio.NewWriter(out)
+	rw.bomDone = true
+	rw.writeRecord(row, w.numericCols(val.Type()), w.nullCols(val))
+	rw.w.Flush()
+	return int64(b.Len())
 }
 
 // WriteStructs takes a slice of structs and writes them as CSV records.  This
-// includes writing out the column names as the first row.  When done, Flush
-// is called.
+// includes writing out the column names as the first row, unless writing the
+// header has been turned off with SetWriteHeader(false) or a header has
+// already been written.  When done, Flush is called.
+//
+// If a field can't be encoded, the OnError func, if one has been set, is
+// called with the struct's index and the error; if it returns nil, writing continues,
+// otherwise its error is returned.  If an OnError func hasn't been set, the
+// Encoder's ErrorPolicy is used: AbortOnError stops writing and returns the
+// error, which is a *FieldError; the other policies continue writing and
+// return a MultiError of all the errors that occurred.
 func (w *Writer) WriteStructs(st interface{}) error {
-	rows, err := w.e.Marshal(st)
+	val, err := structSlice(st)
+	if err != nil {
+		return err
+	}
+	err = w.checkStrict()
 	if err != nil {
 		return err
 	}
-	w.r = len(rows)
-	w.w.WriteAll(rows)
+	if w.writeHdr && !w.hdrDone {
+		err = w.WriteColNames(val.Index(0).Interface())
+	} else {
+		// keep the column names up to date
+		_, err = w.e.GetColNames(val.Index(0).Interface())
+	}
+	if err != nil {
+		return err
+	}
+	var errs MultiError
+	for i := 0; i < val.Len(); i++ {
+		index := w.n
+		w.n++
+		row, err := w.e.GetRow(val.Index(i).Interface())
+		if err != nil {
+			err = rowError(err, index, w.r)
+			switch {
+			case w.onError != nil:
+				err = w.onError(index, err)
+				if err != nil {
+					w.w.Flush()
+					return err
+				}
+			case w.e.policy == AbortOnError:
+				w.w.Flush()
+				return err
+			default:
+				errs = appendErrors(errs, err)
+			}
+		}
+		if row == nil {
+			continue
+		}
+		err = w.writeRow(row, val.Index(i))
+		if err != nil {
+			return err
+		}
+	}
 	w.w.Flush()
-	return w.w.Error()
+	err = w.w.Error()
+	if err != nil {
+		return err
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
 }
 
 // Write takes a slice of strings and writes them as a single CSV record.
+// None of the fields are NULL; fields equal to the Dialect's NULL token are
+// quoted or escaped.
 func (w *Writer) Write(row []string) error {
 	err := w.w.Write(row)
 	if err != nil {
@@ -101,6 +434,17 @@ func (w *Writer) Rows() int {
 	return w.r
 }
 
+// Bytes returns the number of bytes written, including those that are
+// buffered and haven't been flushed yet.  Bytes are counted after
+// transcoding: if a Charset other than UTF8 is used, the buffered bytes are
+// flushed, so that they are transcoded, first.
+func (w *Writer) Bytes() int64 {
+	if w.charset != UTF8 {
+		w.w.Flush()
+	}
+	return w.b + int64(w.w.w.Buffered())
+}
+
 // Expose public CSV fields
 
 // Comma is the field delimiter, set to '.'
@@ -109,11 +453,112 @@ func (w *Writer) Comma() rune {
 }
 
 // SetComma takes the passed rune and uses it to set the field
-// delimiter for CSV fields.
+// delimiter for CSV fields.  If the Writer's NumberFormatter uses the same
+// rune as a separator, CheckDialect will return an error.
 func (w *Writer) SetComma(r rune) {
 	w.w.Comma = r
 }
 
+// CheckDialect checks that the field delimiter is not also used as a
+// separator when formatting numbers, e.g. ',' as both the field delimiter
+// and the decimal separator.  The resulting CSV would still be valid, as the
+// affected fields are quoted, but many consumers won't handle it correctly;
+// use SetComma(';') for locales that use ',' as the decimal separator.
+//
+// If there is a conflict, a SeparatorConflictError is returned.  This is
+// only a warning, unless SetStrictDialect(true) was called.
+func (w *Writer) CheckDialect() error {
+	if w.e.numFmt == nil {
+		return nil
+	}
+	return separatorConflict(w.w.Comma, w.e.numFmt)
+}
+
+// SetStrictDialect sets whether or not writing structs fails, before
+// anything is written, if CheckDialect returns an error.  By default, this
+// is false.  The check is done when writing, so the delimiter and the
+// NumberFormatter can be set in any order.
+func (w *Writer) SetStrictDialect(b bool) {
+	w.strict = b
+}
+
+// checkStrict returns the error returned by CheckDialect if the Writer is
+// strict.
+func (w *Writer) checkStrict() error {
+	if !w.strict {
+		return nil
+	}
+	return w.CheckDialect()
+}
+
+// SetDialect sets the Dialect used to write records.  The Dialect's Null
+// token is also used as the Encoder's null token; NULL values are written
+// as is, while other fields equal to it are quoted or escaped.
+//
+//	w.SetDialect(struct2csv.PostgresCOPY)
+func (w *Writer) SetDialect(d Dialect) {
+	w.w.Dialect = d
+	w.e.SetNullToken(d.Null)
+}
+
+// SetEscape sets the escape character of the Dialect.  Fields that aren't
+// quoted, because of QuoteNone or a Dialect without a quote character, need
+// one to escape the delimiter and line breaks; without one, such fields
+// result in ErrNoEscape.
+//
+//	w.SetQuoting(struct2csv.QuoteNone)
+//	w.SetEscape('\\')
+func (w *Writer) SetEscape(r rune) {
+	w.w.Escape = r
+}
+
+// SetQuoting sets when fields are quoted.  By default, this is QuoteMinimal:
+// fields are only quoted when they contain special characters.
+func (w *Writer) SetQuoting(q QuotePolicy) {
+	w.w.quoting = q
+}
+
+// SetBOM sets whether or not a byte order mark is written before the first
+// record.  This is also set by the Dialect; Excel needs a BOM to detect
+// that a CSV file is UTF-8.  A BOM is only written for the Unicode
+// Charsets.
+func (w *Writer) SetBOM(b bool) {
+	w.w.BOM = b
+}
+
+// SetCharset sets the character encoding of the output.  By default, this
+// is UTF8.  If the output contains a rune that can't be represented in the
+// Charset, writing fails with an UnrepresentableRuneError, unless a
+// replacement has been set using SetCharsetReplacement.  This should be
+// called before anything is written; Append only supports UTF8.
+func (w *Writer) SetCharset(c Charset) {
+	w.charset = c
+	w.setOutput()
+}
+
+// SetCharsetReplacement sets the rune that replaces runes that can't be
+// represented in the Charset, e.g. '?'.  If r is 0, which is the default,
+// unrepresentable runes are an error.
+func (w *Writer) SetCharsetReplacement(r rune) {
+	w.repl = r
+	w.setOutput()
+}
+
+// setOutput sets what the record writer writes to, based on the Charset.
+func (w *Writer) setOutput() {
+	w.w.Flush()
+	if w.charset == UTF8 {
+		w.w.w = bufio.NewWriter(byteCounter{w})
+		return
+	}
+	w.w.w = bufio.NewWriter(newTranscoder(byteCounter{w}, w.charset, w.repl))
+}
+
+// Dialect returns the Dialect used to write records.
+func (w *Writer) Dialect() Dialect {
+	return w.w.Dialect
+}
+
 // UseCRLF exposes the csv writer's UseCRLF field.
 func (w *Writer) UseCRLF() bool {
 	return w.w.UseCRLF
@@ -131,6 +576,18 @@ func (w *Writer) SetTag(s string) {
 	w.e.SetTag(s)
 }
 
+// SetTags set's the tags, in order of precedence, to match on for a struct's
+// field tags.
+func (w *Writer) SetTags(tags ...string) {
+	w.e.SetTags(tags...)
+}
+
+// SetNameMapper sets the func used to transform field names into column
+// names for fields whose name doesn't come from a tag.
+func (w *Writer) SetNameMapper(fn func(string) string) {
+	w.e.SetNameMapper(fn)
+}
+
 // SetUseTags set's whether or not field tag values should be checked.
 // If field tags are not being checked, the field name will be used for
 // the column name.
@@ -144,14 +601,107 @@ func (w *Writer) SetSeparators(beg, end string) {
 	w.e.SetSeparators(beg, end)
 }
 
-// SetBase set's the base for _uint_ values; mainly used for
-// `strings.FormatUint()`. By default, this is set to 10, for base 10
-// numbering.  Any base value < 2 will be set to 2, binary.
+// SetBase set's the base for both signed and unsigned integer values. By
+// default, this is set to 10, for base 10 numbering.  Any base value < 2
+// will be set to 2, binary, and any base value > 36 will be set to 36.
 func (w *Writer) SetBase(i int) {
 	w.e.SetBase(i)
 }
 
+// SetIntBase set's the base for signed integer values.
+func (w *Writer) SetIntBase(i int) {
+	w.e.SetIntBase(i)
+}
+
+// SetUintBase set's the base for unsigned integer values.
+func (w *Writer) SetUintBase(i int) {
+	w.e.SetUintBase(i)
+}
+
 // ColNames returns a copy of the encoder's cached column names
 func (w *Writer) ColNames() []string {
 	return w.e.ColNames()
 }
+
+// ColLabels returns a copy of the encoder's cached column labels
+func (w *Writer) ColLabels() []string {
+	return w.e.ColLabels()
+}
+
+// SetHeaderMode sets whether the header uses the column names or their
+// display labels.
+func (w *Writer) SetHeaderMode(m HeaderMode) {
+	w.e.SetHeaderMode(m)
+}
+
+// SetFloatFormat sets the format and precision used for float values; these
+// are passed to `strconv.FormatFloat`.  By default, 'E' and -1 are used.
+func (w *Writer) SetFloatFormat(fmt byte, prec int) {
+	w.e.SetFloatFormat(fmt, prec)
+}
+
+// SetFloatSpecials sets the strings used for NaN, +Inf, and -Inf float
+// values.
+func (w *Writer) SetFloatSpecials(nan, posInf, negInf string) {
+	w.e.SetFloatSpecials(nan, posInf, negInf)
+}
+
+// SetNumberFormatter sets the NumberFormatter used for base 10 integers and
+// floats.  Use CheckDialect to verify that its separators don't conflict
+// with the field delimiter.
+func (w *Writer) SetNumberFormatter(f NumberFormatter) {
+	w.e.SetNumberFormatter(f)
+}
+
+// SetBoolFormatter sets the BoolFormatter used for bool values.
+func (w *Writer) SetBoolFormatter(f BoolFormatter) {
+	w.e.SetBoolFormatter(f)
+}
+
+// SetBytesEncoding sets how []byte and [N]byte values are encoded; by
+// default they are encoded as a list of numbers.
+func (w *Writer) SetBytesEncoding(enc BytesEncoding) {
+	w.e.SetBytesEncoding(enc)
+}
+
+// RegisterType registers fn as the encoder for values of type typ.
+func (w *Writer) RegisterType(typ reflect.Type, fn EncodeFunc) {
+	w.e.RegisterType(typ, fn)
+}
+
+// SetNullToken sets the value used for nil pointers and NULL values.  It
+// also replaces the Dialect's Null token, so fields equal to it are written
+// as is.
+func (w *Writer) SetNullToken(s string) {
+	w.e.SetNullToken(s)
+	w.w.Null = s
+}
+
+// RegisterFormatter registers fn as the formatter called name; formatters are
+// referenced from field tags using the fmt option.
+func (w *Writer) RegisterFormatter(name string, fn EncodeFunc) {
+	w.e.RegisterFormatter(name, fn)
+}
+
+// SetErrorPolicy sets what happens when a struct can't be encoded.
+func (w *Writer) SetErrorPolicy(p ErrorPolicy) {
+	w.e.SetErrorPolicy(p)
+}
+
+// SetPlaceholder sets the value used for fields that can't be encoded when
+// the ErrorPolicy is PlaceholderOnError.
+func (w *Writer) SetPlaceholder(s string) {
+	w.e.SetPlaceholder(s)
+}
+
+// SetOnError sets the func that is called when a struct can't be encoded.
+// It receives the position of the struct in the input, the number of
+// structs the Writer was given before it, and the error; this is also the
+// Index of the error's *FieldErrors.
+// If it returns nil, the struct is skipped, or written with placeholders if
+// the ErrorPolicy is PlaceholderOnError, and writing continues; otherwise,
+// writing stops and its error is returned.  This can be used to log and
+// quarantine bad records.  A nil func removes the OnError func.
+func (w *Writer) SetOnError(fn func(row int, err error) error) {
+	w.onError = fn
+}
@@ -2,6 +2,11 @@ package struct2csv
 
 import (
 	"bytes"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"os"
+	"reflect"
 	"testing"
 )
 
@@ -171,3 +176,368 @@ func TestWriteStructsComplex(t *testing.T) {
 		t.Errorf("got %q, want %q", s, expected)
 	}
 }
+
+type Refund struct {
+	ID     int
+	Amount int64 `csv:"amount,fmt=cents"`
+}
+
+func TestWriteStructError(t *testing.T) {
+	errNeg := errors.New("negative")
+	buff := &bytes.Buffer{}
+	w := NewWriter(buff)
+	w.RegisterFormatter("cents", func(v reflect.Value) (string, error) {
+		if v.Int() < 0 {
+			return "", errNeg
+		}
+		return fmt.Sprintf("%d.%02d", v.Int()/100, v.Int()%100), nil
+	})
+	err := w.WriteColNames(Refund{})
+	if err != nil {
+		t.Errorf("unexpected error: %s", err)
+	}
+	for i, p := range []Refund{Refund{ID: 1, Amount: 100}, Refund{ID: 2, Amount: -100}} {
+		err = w.WriteStruct(p)
+		if i == 0 {
+			if err != nil {
+				t.Errorf("%d: unexpected error: %s", i, err)
+			}
+			continue
+		}
+		var fe *FieldError
+		if !errors.As(err, &fe) {
+			t.Errorf("%d: expected a *FieldError, got %v", i, err)
+			continue
+		}
+		if fe.Row != 2 || fe.Path != "Amount" || !errors.Is(err, errNeg) {
+			t.Errorf("%d: unexpected error: %#v", i, fe)
+		}
+	}
+}
+
+func TestWriteHeader(t *testing.T) {
+	data := []Basic{Basic{Name: "Leo Tolstoy", List: []string{"War and Peace"}}}
+	buff := &bytes.Buffer{}
+	w := NewWriter(buff)
+	w.SetWriteHeader(false)
+	err := w.WriteStructs(data)
+	if err != nil {
+		t.Errorf("unexpected error: %s", err)
+	}
+	expected := "Leo Tolstoy,War and Peace\n"
+	if buff.String() != expected {
+		t.Errorf("expected %q, got %q", expected, buff.String())
+	}
+
+	buff.Reset()
+	w = NewWriter(buff)
+	err = w.WriteHeader([]string{"Author", "Works"})
+	if err != nil {
+		t.Errorf("unexpected error: %s", err)
+	}
+	err = w.WriteStructs(data)
+	if err != nil {
+		t.Errorf("unexpected error: %s", err)
+	}
+	// the header is only written once
+	err = w.WriteStructs(data)
+	if err != nil {
+		t.Errorf("unexpected error: %s", err)
+	}
+	expected = "Author,Works\nLeo Tolstoy,War and Peace\nLeo Tolstoy,War and Peace\n"
+	if buff.String() != expected {
+		t.Errorf("expected %q, got %q", expected, buff.String())
+	}
+	if w.Rows() != 3 {
+		t.Errorf("expected 3 rows, got %d", w.Rows())
+	}
+}
+
+func TestAppend(t *testing.T) {
+	data := []Basic{Basic{Name: "Ivan Turgenev", List: []string{"Fathers and Sons"}}}
+	tsts := []struct {
+		existing string
+		header   interface{}
+		expected string
+		err      string
+	}{
+		{"", Basic{}, "Nom,Liste\nIvan Turgenev,Fathers and Sons\n", ""},
+		{"Nom,Liste\nA,B\n", Basic{}, "Nom,Liste\nA,B\nIvan Turgenev,Fathers and Sons\n", ""},
+		{"Nom,Liste\nA,B", Basic{}, "Nom,Liste\nA,B\nIvan Turgenev,Fathers and Sons\n", ""},
+		{"Author,Works\nA,B\n", []string{"Author", "Works"}, "Author,Works\nA,B\nIvan Turgenev,Fathers and Sons\n", ""},
+		{"Name,List\nA,B\n", Basic{}, "Name,List\nA,B\n",
+			`struct2csv: header mismatch: expected ["Nom" "Liste"], got ["Name" "List"]`},
+	}
+	for i, tst := range tsts {
+		f, err := os.CreateTemp(t.TempDir(), "append")
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = f.WriteString(tst.existing)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w := NewWriter(f)
+		err = w.Append(f, tst.header)
+		if err == nil {
+			err = w.WriteStructs(data)
+		}
+		if err != nil {
+			if err.Error() != tst.err {
+				t.Errorf("%d: expected error %q, got %q", i, tst.err, err)
+			}
+		} else if tst.err != "" {
+			t.Errorf("%d: expected error %q, got none", i, tst.err)
+		}
+		f.Close()
+		b, err := os.ReadFile(f.Name())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(b) != tst.expected {
+			t.Errorf("%d: expected %q, got %q", i, tst.expected, string(b))
+		}
+	}
+
+	// all the rows of a grouped header are validated
+	contacts := []Contact{Contact{Name: "a"}}
+	f, err := os.CreateTemp(t.TempDir(), "append")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	w := NewWriter(f)
+	w.SetHeaderStyle(HeaderGrouped)
+	err = w.WriteStructs(contacts)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	w = NewWriter(f)
+	w.SetHeaderStyle(HeaderGrouped)
+	err = w.Append(f, Contact{})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	err = w.WriteStructs([]Contact{Contact{Name: "b"}})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	b, err := os.ReadFile(f.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := ",Home,,,,Office,,,,,,,,\n" +
+		",,,Geo,,,,Geo,,,,,,\n" +
+		"Name,Street,City,Lat,Long,Street,City,Lat,Long,Addr1,Addr2,City,State,Zip\n" +
+		"a,,,,,,,,,,,,,\nb,,,,,,,,,,,,,\n"
+	if string(b) != expected {
+		t.Errorf("expected %q, got %q", expected, string(b))
+	}
+	w = NewWriter(f)
+	w.SetHeaderStyle(HeaderGrouped)
+	err = w.Append(f, Basic{})
+	if _, ok := err.(HeaderMismatchError); !ok {
+		t.Errorf("expected a HeaderMismatchError, got %v", err)
+	}
+}
+
+type Contact struct {
+	Name string
+	Home ContactAddress
+	Work ContactAddress `csv:"Office"`
+	Address
+}
+
+type ContactAddress struct {
+	Street string
+	City   string
+	Geo    struct {
+		Lat  string
+		Long string
+	}
+}
+
+func TestHeaderGrouped(t *testing.T) {
+	expected := ",Home,,,,Office,,,,,,,,\n" +
+		",,,Geo,,,,Geo,,,,,,\n" +
+		"Name,Street,City,Lat,Long,Street,City,Lat,Long,Addr1,Addr2,City,State,Zip\n"
+	buff := &bytes.Buffer{}
+	w := NewWriter(buff)
+	w.SetHeaderStyle(HeaderGrouped)
+	err := w.WriteColNames(Contact{})
+	if err != nil {
+		t.Errorf("unexpected error: %s", err)
+	}
+	w.Flush()
+	if buff.String() != expected {
+		t.Errorf("expected %q, got %q", expected, buff.String())
+	}
+	if w.Rows() != 3 {
+		t.Errorf("expected 3 rows, got %d", w.Rows())
+	}
+
+	// structs without nesting have a single header row
+	buff.Reset()
+	w = NewWriter(buff)
+	w.SetHeaderStyle(HeaderGrouped)
+	err = w.WriteStructs([]Basic{Basic{Name: "a"}})
+	if err != nil {
+		t.Errorf("unexpected error: %s", err)
+	}
+	if buff.String() != "Nom,Liste\na,\n" {
+		t.Errorf("expected %q, got %q", "Nom,Liste\na,\n", buff.String())
+	}
+}
+
+func TestDialect(t *testing.T) {
+	row := []string{"a\tb", "line1\nline2", `C:\tmp`, `say "hi"`, `\N`}
+	tsts := []struct {
+		name     string
+		d        Dialect
+		expected string
+	}{
+		{"default", DefaultDialect, "a\tb,\"line1\nline2\",C:\\tmp,\"say \"\"hi\"\"\",\\N\n"},
+		{"rfc4180", RFC4180, "a\tb,\"line1\r\nline2\",C:\\tmp,\"say \"\"hi\"\"\",\\N\r\n"},
+		{"excel", Excel, "\uFEFFa\tb,\"line1\r\nline2\",C:\\tmp,\"say \"\"hi\"\"\",\\N\r\n"},
+		{"tsv", TSV, `a\tb` + "\t" + `line1\nline2` + "\t" + `C:\\tmp` + "\t" + `say "hi"` + "\t" + `\\N` + "\n"},
+		// a field equal to the NULL token isn't NULL
+		{"postgres", PostgresCOPY, `a\tb` + "\t" + `line1\nline2` + "\t" + `C:\\tmp` + "\t" + `say "hi"` + "\t" + `\\N` + "\n"},
+		{"mysql quoted", Dialect{Comma: ',', Quote: '"', Escape: '\\', Null: `\N`}, "a\tb,\"line1\nline2\",C:\\tmp,\"say \\\"hi\\\"\",\"\\\\N\"\n"},
+	}
+	for _, test := range tsts {
+		buff := &bytes.Buffer{}
+		w := NewWriter(buff)
+		w.SetDialect(test.d)
+		err := w.Write(row)
+		if err != nil {
+			t.Errorf("%s: unexpected error: %s", test.name, err)
+			continue
+		}
+		w.Flush()
+		if buff.String() != test.expected {
+			t.Errorf("%s: expected %q, got %q", test.name, test.expected, buff.String())
+		}
+	}
+
+	// the Dialect's Null token is used for nil pointers
+	type Nullable struct {
+		Name *string
+		Note string
+	}
+	buff := &bytes.Buffer{}
+	w := NewWriter(buff)
+	w.SetDialect(PostgresCOPY)
+	name := `\N`
+	err := w.WriteStructs([]Nullable{Nullable{Note: "x\ty"}, Nullable{Name: &name, Note: `\N`}})
+	if err != nil {
+		t.Errorf("unexpected error: %s", err)
+	}
+	expected := "Name\tNote\n\\N\tx\\ty\n\\\\N\t\\\\N\n"
+	if buff.String() != expected {
+		t.Errorf("expected %q, got %q", expected, buff.String())
+	}
+}
+
+// The default dialect writes what encoding/csv does.
+func TestDefaultDialect(t *testing.T) {
+	records := [][]string{
+		[]string{"a", "", " b", "c,d", `say "hi"`, "\\\"\x00"},
+		[]string{"\x00", "x\r\ny", "line1\nline2", `\.`, `\N`, "a\tb"},
+	}
+	for i, record := range records {
+		var expected, got bytes.Buffer
+		cw := csv.NewWriter(&expected)
+		cw.Write(record)
+		cw.Flush()
+		w := NewWriter(&got)
+		err := w.Write(record)
+		if err != nil {
+			t.Errorf("%d: unexpected error: %s", i, err)
+			continue
+		}
+		w.Flush()
+		if got.String() != expected.String() {
+			t.Errorf("%d: expected %q, got %q", i, expected.String(), got.String())
+		}
+	}
+}
+
+func TestQuoting(t *testing.T) {
+	type Item struct {
+		SKU   string
+		Qty   int
+		Price *float64
+		Note  string
+	}
+	price := 1.5
+	data := []Item{Item{SKU: "A-1", Qty: 2, Price: &price, Note: "a, b"}, Item{SKU: "B-2", Qty: 1000}}
+	tsts := []struct {
+		q        QuotePolicy
+		d        Dialect
+		expected string
+	}{
+		{QuoteMinimal, DefaultDialect, "SKU,Qty,Price,Note\nA-1,2,1.5E+00,\"a, b\"\nB-2,1000,,\n"},
+		{QuoteAll, DefaultDialect, "\"SKU\",\"Qty\",\"Price\",\"Note\"\n\"A-1\",\"2\",\"1.5E+00\",\"a, b\"\n\"B-2\",\"1000\",\"\",\"\"\n"},
+		{QuoteNonNumeric, DefaultDialect, "\"SKU\",\"Qty\",\"Price\",\"Note\"\n\"A-1\",2,1.5E+00,\"a, b\"\n\"B-2\",1000,,\"\"\n"},
+		{QuoteNone, Dialect{Comma: ',', Quote: '"', Escape: '\\'}, "SKU,Qty,Price,Note\nA-1,2,1.5E+00,a\\, b\nB-2,1000,,\n"},
+		// NULL values aren't quoted
+		{QuoteAll, Dialect{Comma: ',', Quote: '"', Null: "NULL"}, "\"SKU\",\"Qty\",\"Price\",\"Note\"\n\"A-1\",\"2\",\"1.5E+00\",\"a, b\"\n\"B-2\",\"1000\",NULL,\"\"\n"},
+	}
+	for i, test := range tsts {
+		buff := &bytes.Buffer{}
+		w := NewWriter(buff)
+		w.SetDialect(test.d)
+		w.SetQuoting(test.q)
+		err := w.WriteStructs(data)
+		if err != nil {
+			t.Errorf("%d: unexpected error: %s", i, err)
+			continue
+		}
+		if buff.String() != test.expected {
+			t.Errorf("%d: expected %q, got %q", i, test.expected, buff.String())
+		}
+	}
+
+	// QuoteNone without an escape character can't write the delimiter
+	buff := &bytes.Buffer{}
+	w := NewWriter(buff)
+	w.SetQuoting(QuoteNone)
+	err := w.Write([]string{"a\tb", "c"})
+	if err != nil {
+		t.Errorf("unexpected error: %s", err)
+	}
+	err = w.Write([]string{"a,b", "c"})
+	if err != ErrNoEscape {
+		t.Errorf("expected %v, got %v", ErrNoEscape, err)
+	}
+	w.SetEscape('\\')
+	err = w.Write([]string{"a,b", "c"})
+	if err != nil {
+		t.Errorf("unexpected error: %s", err)
+	}
+	w.Flush()
+	expected := "a\tb,c\n" + `a\,b,c` + "\n"
+	if buff.String() != expected {
+		t.Errorf("expected %q, got %q", expected, buff.String())
+	}
+
+	// the quote character is escaped, as readers of the dialect would
+	// treat it as quoting
+	buff.Reset()
+	w = NewWriter(buff)
+	w.SetQuoting(QuoteNone)
+	err = w.Write([]string{`"q"`})
+	if err != ErrNoEscape {
+		t.Errorf("expected %v, got %v", ErrNoEscape, err)
+	}
+	w.SetEscape('\\')
+	err = w.Write([]string{`"q"`})
+	if err != nil {
+		t.Errorf("unexpected error: %s", err)
+	}
+	w.Flush()
+	expected = `\"q\"` + "\n"
+	if buff.String() != expected {
+		t.Errorf("expected %q, got %q", expected, buff.String())
+	}
+}
@@ -0,0 +1,211 @@
+package struct2csv
+
+import (
+	"archive/zip"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// A SheetNameError is returned when a sheet name isn't valid: sheet names
+// must be unique, between 1 and 31 characters long, and can't contain any
+// of []:*?/\.
+type SheetNameError struct {
+	Name string
+}
+
+func (e SheetNameError) Error() string {
+	return fmt.Sprintf("struct2csv: invalid sheet name: %q", e.Name)
+}
+
+// An XLSXWriter writes slices of structs as the sheets of an Office Open
+// XML workbook, i.e. an .xlsx file.  Cells are typed according to the kinds
+// of the struct's fields: ints, uints, and floats are numbers, bools are
+// booleans, and time.Time values are dates; everything else is text, encoded
+// as it would be for CSV.  The header row of each sheet is frozen.
+//
+// The workbook isn't complete until Close is called.
+type XLSXWriter struct {
+	e      *Encoder
+	z      *zip.Writer
+	sheets []string
+}
+
+// NewXLSXWriter returns a new XLSXWriter that writes to w.
+func NewXLSXWriter(w io.Writer) *XLSXWriter {
+	return &XLSXWriter{e: New(), z: zip.NewWriter(w)}
+}
+
+// SetEncoder sets the Encoder used to encode structs.
+func (w *XLSXWriter) SetEncoder(e *Encoder) {
+	w.e = e
+}
+
+// WriteSheet writes v, a slice of structs, as a sheet called name, with the
+// column names as the header.  If a field can't be encoded, what happens
+// depends on the Encoder's ErrorPolicy, as it does for Encoder.Marshal; if
+// the policy isn't AbortOnError, the sheet is written and the errors are
+// returned.
+func (w *XLSXWriter) WriteSheet(name string, v interface{}) error {
+	if !w.validSheetName(name) {
+		return SheetNameError{Name: name}
+	}
+	hdr, rows, err := w.e.typedData(v)
+	if hdr == nil {
+		return err
+	}
+	f, werr := w.z.Create(fmt.Sprintf("xl/worksheets/sheet%d.xml", len(w.sheets)+1))
+	if werr != nil {
+		return werr
+	}
+	var b strings.Builder
+	b.WriteString(xml.Header)
+	b.WriteString(`<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">`)
+	b.WriteString(`<sheetViews><sheetView workbookViewId="0">`)
+	b.WriteString(`<pane ySplit="1" topLeftCell="A2" activePane="bottomLeft" state="frozen"/>`)
+	b.WriteString(`</sheetView></sheetViews><sheetData>`)
+	b.WriteString(`<row r="1">`)
+	for i, name := range hdr {
+		writeCell(&b, cellRef(i, 1), name)
+	}
+	b.WriteString(`</row>`)
+	for r, row := range rows {
+		fmt.Fprintf(&b, `<row r="%d">`, r+2)
+		for i, v := range row {
+			writeCell(&b, cellRef(i, r+2), v)
+		}
+		b.WriteString(`</row>`)
+	}
+	b.WriteString(`</sheetData></worksheet>`)
+	_, werr = io.WriteString(f, b.String())
+	if werr != nil {
+		return werr
+	}
+	w.sheets = append(w.sheets, name)
+	return err
+}
+
+// validSheetName returns whether or not name can be used for a new sheet.
+func (w *XLSXWriter) validSheetName(name string) bool {
+	if name == "" || len([]rune(name)) > 31 || strings.ContainsAny(name, `[]:*?/\`) {
+		return false
+	}
+	for _, s := range w.sheets {
+		if strings.EqualFold(s, name) {
+			return false
+		}
+	}
+	return true
+}
+
+// Close writes the rest of the workbook and closes it.  It doesn't close
+// the underlying io.Writer.
+func (w *XLSXWriter) Close() error {
+	var sheets, rels, types strings.Builder
+	for i, name := range w.sheets {
+		fmt.Fprintf(&sheets, `<sheet name="%s" sheetId="%d" r:id="rId%d"/>`, xmlEscape(name), i+1, i+1)
+		fmt.Fprintf(&rels, `<Relationship Id="rId%d" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet%d.xml"/>`, i+1, i+1)
+		fmt.Fprintf(&types, `<Override PartName="/xl/worksheets/sheet%d.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/>`, i+1)
+	}
+	n := len(w.sheets)
+	files := []struct {
+		name, body string
+	}{
+		{"[Content_Types].xml", `<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">` +
+			`<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>` +
+			`<Default Extension="xml" ContentType="application/xml"/>` +
+			`<Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/>` +
+			`<Override PartName="/xl/styles.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.styles+xml"/>` +
+			types.String() + `</Types>`},
+		{"_rels/.rels", `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+			`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/>` +
+			`</Relationships>`},
+		{"xl/workbook.xml", `<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">` +
+			`<sheets>` + sheets.String() + `</sheets></workbook>`},
+		{"xl/_rels/workbook.xml.rels", `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+			rels.String() +
+			fmt.Sprintf(`<Relationship Id="rId%d" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/styles" Target="styles.xml"/>`, n+1) +
+			`</Relationships>`},
+		{"xl/styles.xml", xlsxStyles},
+	}
+	for _, file := range files {
+		f, err := w.z.Create(file.name)
+		if err != nil {
+			return err
+		}
+		_, err = io.WriteString(f, xml.Header+file.body)
+		if err != nil {
+			return err
+		}
+	}
+	return w.z.Close()
+}
+
+// xlsxStyles has two cell formats: the default, and dates, which is used by
+// cells with a style of 1.
+const xlsxStyles = `<styleSheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">` +
+	`<numFmts count="1"><numFmt numFmtId="164" formatCode="yyyy-mm-dd hh:mm:ss"/></numFmts>` +
+	`<fonts count="1"><font><sz val="11"/><name val="Calibri"/></font></fonts>` +
+	`<fills count="2"><fill><patternFill patternType="none"/></fill><fill><patternFill patternType="gray125"/></fill></fills>` +
+	`<borders count="1"><border><left/><right/><top/><bottom/><diagonal/></border></borders>` +
+	`<cellStyleXfs count="1"><xf numFmtId="0" fontId="0" fillId="0" borderId="0"/></cellStyleXfs>` +
+	`<cellXfs count="2"><xf numFmtId="0" fontId="0" fillId="0" borderId="0" xfId="0"/>` +
+	`<xf numFmtId="164" fontId="0" fillId="0" borderId="0" xfId="0" applyNumberFormat="1"/></cellXfs>` +
+	`</styleSheet>`
+
+// excelEpoch is day 0 of Excel's 1900 date system, accounting for Excel
+// treating 1900 as a leap year.
+var excelEpoch = time.Date(1899, 12, 30, 0, 0, 0, 0, time.UTC)
+
+// writeCell writes the cell at ref with the value v.  NULL values result
+// in an empty cell, which isn't written.
+func writeCell(b *strings.Builder, ref string, v interface{}) {
+	switch v := v.(type) {
+	case nil:
+	case bool:
+		s := "0"
+		if v {
+			s = "1"
+		}
+		fmt.Fprintf(b, `<c r="%s" t="b"><v>%s</v></c>`, ref, s)
+	case int64:
+		fmt.Fprintf(b, `<c r="%s"><v>%d</v></c>`, ref, v)
+	case uint64:
+		fmt.Fprintf(b, `<c r="%s"><v>%d</v></c>`, ref, v)
+	case float64:
+		fmt.Fprintf(b, `<c r="%s"><v>%s</v></c>`, ref, strconv.FormatFloat(v, 'g', -1, 64))
+	case time.Time:
+		// dates are the number of days since the epoch, using the time's
+		// wall clock.  Excel doesn't have dates before 1900, so those are
+		// written as text.
+		wall := time.Date(v.Year(), v.Month(), v.Day(), v.Hour(), v.Minute(), v.Second(), v.Nanosecond(), time.UTC)
+		if wall.Year() < 1900 {
+			writeCell(b, ref, v.Format(time.RFC3339Nano))
+			return
+		}
+		days := float64(wall.Unix()-excelEpoch.Unix())/86400 + float64(wall.Nanosecond())/(86400*1e9)
+		fmt.Fprintf(b, `<c r="%s" s="1"><v>%s</v></c>`, ref, strconv.FormatFloat(days, 'f', -1, 64))
+	case string:
+		fmt.Fprintf(b, `<c r="%s" t="inlineStr"><is><t xml:space="preserve">%s</t></is></c>`, ref, xmlEscape(v))
+	}
+}
+
+// cellRef returns the A1 style reference of the cell in column col, which
+// is 0 based, and row, which is 1 based.
+func cellRef(col, row int) string {
+	var name []byte
+	for col++; col > 0; col = (col - 1) / 26 {
+		name = append([]byte{byte('A' + (col-1)%26)}, name...)
+	}
+	return string(name) + strconv.Itoa(row)
+}
+
+// xmlEscape returns s with XML special characters escaped.
+func xmlEscape(s string) string {
+	var b strings.Builder
+	xml.EscapeText(&b, []byte(s))
+	return b.String()
+}
@@ -0,0 +1,138 @@
+package struct2csv
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+type Trade struct {
+	ID      int       `csv:"id"`
+	Price   float32   `csv:"price"`
+	Settled bool      `csv:"settled"`
+	At      time.Time `csv:"at"`
+	Note    *string   `csv:"note"`
+}
+
+func TestXLSXWriter(t *testing.T) {
+	note := "a < b"
+	at := time.Date(2015, 11, 22, 12, 0, 0, 0, time.UTC)
+	buff := &bytes.Buffer{}
+	w := NewXLSXWriter(buff)
+	err := w.WriteSheet("Trades", []Trade{Trade{ID: 1, Price: 0.1, Settled: true, At: at, Note: &note}, Trade{ID: 2}})
+	if err != nil {
+		t.Errorf("unexpected error: %s", err)
+	}
+	err = w.WriteSheet("Stocks", stocks)
+	if err != nil {
+		t.Errorf("unexpected error: %s", err)
+	}
+	err = w.WriteSheet("Sites", []Site{Site{Name: "a", Addr: &Address{City: "Springfield"}}, Site{Name: "b"}})
+	if err != nil {
+		t.Errorf("unexpected error: %s", err)
+	}
+	for _, name := range []string{"", "trades", "a/b", strings.Repeat("x", 32)} {
+		err = w.WriteSheet(name, stocks)
+		if _, ok := err.(SheetNameError); !ok {
+			t.Errorf("%q: expected a SheetNameError, got %v", name, err)
+		}
+	}
+	err = w.Close()
+	if err != nil {
+		t.Errorf("unexpected error: %s", err)
+	}
+
+	z, err := zip.NewReader(bytes.NewReader(buff.Bytes()), int64(buff.Len()))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	files := map[string]string{}
+	for _, f := range z.File {
+		r, err := f.Open()
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %s", f.Name, err)
+		}
+		b, err := io.ReadAll(r)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %s", f.Name, err)
+		}
+		files[f.Name] = string(b)
+		// each part must be well formed
+		d := xml.NewDecoder(bytes.NewReader(b))
+		for {
+			_, err = d.Token()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				t.Errorf("%s: %s", f.Name, err)
+				break
+			}
+		}
+	}
+	for _, name := range []string{"[Content_Types].xml", "_rels/.rels", "xl/workbook.xml", "xl/_rels/workbook.xml.rels", "xl/styles.xml", "xl/worksheets/sheet1.xml", "xl/worksheets/sheet2.xml", "xl/worksheets/sheet3.xml"} {
+		if _, ok := files[name]; !ok {
+			t.Errorf("expected %s in the workbook", name)
+		}
+	}
+	if !strings.Contains(files["xl/workbook.xml"], `<sheet name="Stocks" sheetId="2" r:id="rId2"/>`) {
+		t.Errorf("expected the Stocks sheet, got %s", files["xl/workbook.xml"])
+	}
+	sheet := files["xl/worksheets/sheet1.xml"]
+	for _, cell := range []string{
+		`state="frozen"`,
+		`<c r="A1" t="inlineStr"><is><t xml:space="preserve">id</t></is></c>`,
+		`<c r="A2"><v>1</v></c>`,
+		`<c r="B2"><v>0.1</v></c>`,
+		`<c r="C2" t="b"><v>1</v></c>`,
+		`<c r="D2" s="1"><v>42330.5</v></c>`,
+		`<c r="E2" t="inlineStr"><is><t xml:space="preserve">a &lt; b</t></is></c>`,
+		// dates before 1900 are text, and the nil note isn't written
+		`<c r="D3" t="inlineStr"><is><t xml:space="preserve">0001-01-01T00:00:00Z</t></is></c></row>`,
+	} {
+		if !strings.Contains(sheet, cell) {
+			t.Errorf("expected %s in %s", cell, sheet)
+		}
+	}
+	// both sites are written, the columns of the nil address are empty
+	sheet = files["xl/worksheets/sheet3.xml"]
+	for _, cell := range []string{
+		`<c r="D2" t="inlineStr"><is><t xml:space="preserve">Springfield</t></is></c>`,
+		`<c r="A3" t="inlineStr"><is><t xml:space="preserve">b</t></is></c></row>`,
+	} {
+		if !strings.Contains(sheet, cell) {
+			t.Errorf("expected %s in %s", cell, sheet)
+		}
+	}
+}
+
+func TestCellRef(t *testing.T) {
+	tsts := []struct {
+		col, row int
+		expected string
+	}{
+		{0, 1, "A1"}, {25, 2, "Z2"}, {26, 3, "AA3"}, {701, 4, "ZZ4"}, {702, 5, "AAA5"},
+	}
+	for _, test := range tsts {
+		if ref := cellRef(test.col, test.row); ref != test.expected {
+			t.Errorf("expected %s, got %s", test.expected, ref)
+		}
+	}
+}
+
+func TestTimeField(t *testing.T) {
+	at := time.Date(2015, 11, 22, 12, 0, 0, 0, time.UTC)
+	enc := New()
+	rows, err := enc.Marshal([]Trade{Trade{ID: 1, At: at}})
+	if err != nil {
+		t.Errorf("unexpected error: %s", err)
+	}
+	expected := [][]string{{"id", "price", "settled", "at", "note"}, {"1", "0E+00", "false", "2015-11-22T12:00:00Z", ""}}
+	if len(rows) != 2 || strings.Join(rows[0], ",") != strings.Join(expected[0], ",") || strings.Join(rows[1], ",") != strings.Join(expected[1], ",") {
+		t.Errorf("expected %q, got %q", expected, rows)
+	}
+}